@@ -1,6 +1,9 @@
 package testutils
 
-import "math/rand"
+import (
+	"math/rand"
+	"time"
+)
 
 type MockConfigurationA struct {
 	Value string
@@ -45,6 +48,70 @@ type MockConfigurationWithTwoDepthLevels struct {
 	Second MockConfigurationWithOneDepthLevel
 }
 
+type MockConfigurationWithTags struct {
+	StorageConfig MockConfigurationA `mapstructure:"storage_config" json:"storageConfig"`
+	IngestConfig  MockConfigurationB `json:"ingest_config,omitempty"`
+	Ignored       MockConfigurationB `mapstructure:"-"`
+}
+
+type MockConfigurationPipeline struct {
+	Name  string `dynconf:"key"`
+	Value string
+}
+
+type MockConfigurationWithCollections struct {
+	Tenants   map[string]MockConfigurationA
+	Shards    []MockConfigurationB
+	Pipelines []*MockConfigurationPipeline
+}
+
+type MockConfigurationWithLeaves struct {
+	LogLevel string
+	Timeout  time.Duration
+	Ratio    float64
+	Enabled  bool
+	Hosts    []string
+	Extra    any
+	secret   string
+}
+
+type MockConfigurationLimits struct {
+	Memory int
+	CPU    int
+}
+
+type MockConfigurationService struct {
+	Limits   MockConfigurationLimits
+	Ratio    float64
+	Hook     func()
+	Revision int `dynconf:"ignore"`
+}
+
+type MockConfigurationWithService struct {
+	Service MockConfigurationService
+}
+
+type MockConfigurationServer struct {
+	Port     int `dynconf:"static"`
+	DataDir  string
+	LogLevel string
+}
+
+type MockConfigurationWithServer struct {
+	Server MockConfigurationServer
+}
+
+type MockConfigurationDatabase struct {
+	Address  string
+	Password string `dynconf:"secret"`
+}
+
+type MockConfigurationWithDatabase struct {
+	Database MockConfigurationDatabase
+	Replica  *MockConfigurationDatabase
+	Extra    any
+}
+
 func randomString() string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	result := make([]byte, 5)
@@ -108,3 +175,42 @@ func RandomMockConfigurationWithTwoDepthLevels() MockConfigurationWithTwoDepthLe
 		Second: RandomMockConfigurationWithOneDepthLevel(),
 	}
 }
+
+func RandomMockConfigurationWithTags() MockConfigurationWithTags {
+	return MockConfigurationWithTags{
+		StorageConfig: RandomMockConfigurationA(),
+		IngestConfig:  MockConfigurationB{Value: randomBool()},
+		Ignored:       MockConfigurationB{Value: randomBool()},
+	}
+}
+
+func RandomMockConfigurationWithCollections() MockConfigurationWithCollections {
+	return MockConfigurationWithCollections{
+		Tenants: map[string]MockConfigurationA{
+			"acme":    RandomMockConfigurationA(),
+			"initech": RandomMockConfigurationA(),
+		},
+		Shards: []MockConfigurationB{{Value: randomBool()}, {Value: randomBool()}},
+		Pipelines: []*MockConfigurationPipeline{
+			{Name: "logs", Value: randomString()},
+			{Name: "traces", Value: randomString()},
+		},
+	}
+}
+
+func RandomMockConfigurationWithLeaves() MockConfigurationWithLeaves {
+	return MockConfigurationWithLeaves{
+		LogLevel: randomString(),
+		Timeout:  time.Duration(rand.Intn(1000)) * time.Millisecond,
+		Ratio:    rand.Float64(),
+		Enabled:  randomBool(),
+		Hosts:    []string{randomString(), randomString()},
+		secret:   randomString(),
+	}
+}
+
+func RandomMockConfigurationWithDatabase() MockConfigurationWithDatabase {
+	return MockConfigurationWithDatabase{
+		Database: MockConfigurationDatabase{Address: randomString(), Password: randomString()},
+	}
+}
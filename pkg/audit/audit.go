@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+type Outcome string
+
+const (
+	// The configuration update was applied.
+	OutcomeApplied Outcome = "applied"
+	// The configuration update was rejected by a registered module, or failed within the manager.
+	OutcomeRejected Outcome = "rejected"
+	// The configuration update was superseded by a later one before it started, see manager.Options.CoalesceUpdates.
+	OutcomeSuperseded Outcome = "superseded"
+	// The configuration couldn't be loaded, e.g. the file couldn't be parsed, so it never reached the manager.
+	OutcomeFailedToLoad Outcome = "failed_to_load"
+)
+
+// A record of a single attempted configuration update.
+type Record struct {
+	Time time.Time `json:"time"`
+	// The version of the configuration: the new version if the update was applied, and the current version otherwise.
+	Version uint64 `json:"version"`
+	// The source of the configuration update, such as a file path or an API (see manager.WithSource).
+	Source   string        `json:"source"`
+	Duration time.Duration `json:"duration"`
+	// The changes made by the update, or which it would have made if it wasn't applied.
+	Changes []Change `json:"changes,omitempty"`
+	Outcome Outcome  `json:"outcome"`
+	// The error with which the update failed, if it wasn't applied.
+	Error string `json:"error,omitempty"`
+}
+
+// A change of a single part of the configuration, see manager.Change. The values of secret fields are redacted.
+type Change struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// Where audit records are written to.
+type Sink interface {
+	Write(record Record) error
+}
+
+func changesOf(changes []manager.Change) []Change {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	records := make([]Change, 0, len(changes))
+	for _, change := range changes {
+		records = append(records, Change{
+			Path: change.Path,
+			Kind: change.Kind.String(),
+			Old:  redact(change.Old, change.Secret),
+			New:  redact(change.New, change.Secret),
+		})
+	}
+
+	return records
+}
+
+// Hide the value if it is secret, or else the secret fields within it.
+func redact(value any, secret bool) any {
+	if value == nil || !secret {
+		return manager.Redact(value)
+	}
+
+	return manager.RedactedValue
+}
@@ -0,0 +1,173 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/audit"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func readRecords(t *testing.T, path string) []audit.Record {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit file: %v", err)
+	}
+	defer file.Close()
+
+	records := make([]audit.Record, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("failed to unmarshal audit record %s: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestAuditObserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := audit.NewFileSink(path)
+	if err != nil {
+		t.Fatalf("failed to open file sink: %v", err)
+	}
+	defer sink.Close()
+
+	sinkErrors := make([]error, 0)
+	observer := audit.NewObserver(sink, func(err error) { sinkErrors = append(sinkErrors, err) })
+
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testAuditObserver",
+		manager.Options{Observers: []manager.Observer{observer}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %v", err)
+	}
+
+	initialA := mockConfiguration.A
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		if cfg != initialA {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	oldValue := mockConfiguration.A.Value
+	mockConfiguration.A.Value += "bla"
+	ctx := manager.WithSource(context.Background(), "admin")
+	if err := mgr.OnConfigurationUpdateContext(ctx, mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %v", err)
+	}
+
+	observer.FailedToLoad("config.yaml", errors.New("bad yaml"))
+
+	if len(sinkErrors) != 0 {
+		t.Fatalf("failed to write audit records: %v", sinkErrors)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 audit records but got %#v", records)
+	}
+
+	if records[0].Outcome != audit.OutcomeApplied || records[0].Version != 1 || records[0].Time.IsZero() {
+		t.Fatalf("wrong record of applied update: %#v", records[0])
+	}
+
+	rejected := records[1]
+	if rejected.Outcome != audit.OutcomeRejected || rejected.Version != 1 || rejected.Source != "admin" ||
+		rejected.Error == "" {
+		t.Fatalf("wrong record of rejected update: %#v", rejected)
+	}
+	expectedChange := audit.Change{Path: "A.Value", Kind: "modified", Old: oldValue, New: mockConfiguration.A.Value}
+	if len(rejected.Changes) != 1 || rejected.Changes[0] != expectedChange {
+		t.Fatalf("expected changes of rejected update to be %#v, but got %#v", expectedChange, rejected.Changes)
+	}
+
+	if records[2].Outcome != audit.OutcomeFailedToLoad || records[2].Source != "config.yaml" ||
+		records[2].Error != "bad yaml" {
+		t.Fatalf("wrong record of configuration which failed to load: %#v", records[2])
+	}
+}
+
+func TestAuditRecordValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := audit.NewFileSink(path)
+	if err != nil {
+		t.Fatalf("failed to open file sink: %v", err)
+	}
+	defer sink.Close()
+
+	sinkErrors := make([]error, 0)
+	observer := audit.NewObserver(sink, func(err error) { sinkErrors = append(sinkErrors, err) })
+
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithDatabase](
+		"testAuditRecordValues",
+		manager.Options{Observers: []manager.Observer{observer}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithDatabase()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %v", err)
+	}
+
+	newConfiguration := mockConfiguration
+	newConfiguration.Database.Password += "new"
+	newConfiguration.Replica = &testutils.MockConfigurationDatabase{Address: "replica", Password: "replica-password"}
+	newConfiguration.Extra = func() {}
+	if err := mgr.OnConfigurationUpdate(newConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	observer.OnUpdateSuperseded(manager.UpdateEvent{Version: 2, Err: manager.ErrUpdateSuperseded})
+
+	if len(sinkErrors) != 0 {
+		t.Fatalf("failed to write audit records: %v", sinkErrors)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 audit records but got %#v", records)
+	}
+
+	changes := records[1].Changes
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes but got %#v", changes)
+	}
+	if changes[0].Path != "Database.Password" || changes[0].Old != manager.RedactedValue ||
+		changes[0].New != manager.RedactedValue {
+		t.Fatalf("expected the secret change to be redacted, but got %#v", changes[0])
+	}
+	replica, ok := changes[1].New.(map[string]any)
+	if changes[1].Path != "Replica" || !ok || replica["Address"] != "replica" || replica["Password"] != "" {
+		t.Fatalf("expected the secret field of the added replica to be redacted, but got %#v", changes[1])
+	}
+	if extra, ok := changes[2].New.(string); changes[2].Path != "Extra" || !ok || extra == "" {
+		t.Fatalf("expected the func to be formatted, but got %#v", changes[2])
+	}
+
+	superseded := records[2]
+	if superseded.Outcome != audit.OutcomeSuperseded || superseded.Version != 2 ||
+		superseded.Error != manager.ErrUpdateSuperseded.Error() {
+		t.Fatalf("wrong record of superseded update: %#v", superseded)
+	}
+}
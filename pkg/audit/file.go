@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// A sink which appends records to a file as JSON lines, one record per line.
+type FileSink struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// Open the file for appending records, creating it if it doesn't exist.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file %s: %w", path, err)
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+func (sink *FileSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		// Values which can't be marshalled, e.g. funcs or NaN floats, are written as formatted by fmt instead, so that
+		// the rest of the record isn't lost.
+		if line, err = json.Marshal(withFormattedValues(record)); err != nil {
+			return fmt.Errorf("failed to marshal audit record: %w", err)
+		}
+	}
+	line = append(line, '\n')
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	if _, err := sink.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
+
+// Copy the record, replacing the values of its changes which can't be marshalled by their fmt formatting.
+func withFormattedValues(record Record) Record {
+	changes := make([]Change, 0, len(record.Changes))
+	for _, change := range record.Changes {
+		change.Old, change.New = marshallable(change.Old), marshallable(change.New)
+		changes = append(changes, change)
+	}
+	record.Changes = changes
+
+	return record
+}
+
+func marshallable(value any) any {
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return value
+}
+
+func (sink *FileSink) Close() error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	return sink.file.Close()
+}
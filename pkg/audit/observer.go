@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+// A manager observer which writes a record of every applied, rejected or superseded configuration update to the sink.
+type Observer struct {
+	manager.BaseObserver
+
+	sink    Sink
+	onError func(error)
+}
+
+// Create an observer which writes records to the sink. Errors of the sink are passed to onError, which may be nil.
+func NewObserver(sink Sink, onError func(error)) *Observer {
+	return &Observer{sink: sink, onError: onError}
+}
+
+func (observer *Observer) OnUpdateApplied(event manager.UpdateEvent) {
+	observer.write(event, OutcomeApplied)
+}
+
+func (observer *Observer) OnUpdateRolledBack(event manager.UpdateEvent) {
+	observer.write(event, OutcomeRejected)
+}
+
+func (observer *Observer) OnUpdateSuperseded(event manager.UpdateEvent) {
+	observer.write(event, OutcomeSuperseded)
+}
+
+func (observer *Observer) write(event manager.UpdateEvent, outcome Outcome) {
+	record := Record{
+		Time:     event.Started.Add(event.Duration),
+		Version:  event.Version,
+		Source:   event.Source,
+		Duration: event.Duration,
+		Changes:  changesOf(event.Changes),
+		Outcome:  outcome,
+	}
+	if event.Err != nil {
+		record.Error = event.Err.Error()
+	}
+
+	observer.report(observer.sink.Write(record))
+}
+
+// Write a record of a configuration which couldn't be loaded, and so never reached the manager.
+func (observer *Observer) FailedToLoad(source string, err error) {
+	observer.report(observer.sink.Write(Record{
+		Time:    time.Now(),
+		Source:  source,
+		Outcome: OutcomeFailedToLoad,
+		Error:   err.Error(),
+	}))
+}
+
+func (observer *Observer) report(err error) {
+	if err != nil && observer.onError != nil {
+		observer.onError(err)
+	}
+}
@@ -1,12 +1,27 @@
 package getter
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+var (
+	// Registering with options through a gettable which doesn't support them returns this error.
+	ErrRegisterOptionsNotSupported = errors.New("register options not supported")
+)
 
 type DynamicConfigurationGettable interface {
-	Register(path []string, callback any) error
+	Register(path []string, callback any) (*manager.Subscription, error)
 	Get(path []string, out any) error
 }
 
+// A gettable which can also register with options, such as the manager. Getters use it when it is implemented.
+type DynamicConfigurationGettableWithOptions interface {
+	RegisterWithOptions(path []string, callback any, options manager.RegisterOptions) (*manager.Subscription, error)
+}
+
 type MockDynamicConfigurationGettable struct {
 	register func(path []string, callback any) error
 	get      func(path []string, out any) error
@@ -29,8 +44,22 @@ func NewNoopDynamicConfigurationGettable() *MockDynamicConfigurationGettable {
 	}
 }
 
-func (gettable *MockDynamicConfigurationGettable) Register(path []string, callback any) error {
-	return gettable.register(path, callback)
+// The returned subscription does nothing when closed.
+func (gettable *MockDynamicConfigurationGettable) Register(path []string, callback any) (*manager.Subscription, error) {
+	if err := gettable.register(path, callback); err != nil {
+		return nil, err
+	}
+
+	return manager.NewSubscription(nil), nil
+}
+
+// The options are ignored by the mock.
+func (gettable *MockDynamicConfigurationGettable) RegisterWithOptions(
+	path []string,
+	callback any,
+	options manager.RegisterOptions,
+) (*manager.Subscription, error) {
+	return gettable.Register(path, callback)
 }
 
 func (gettable *MockDynamicConfigurationGettable) Get(path []string, out any) error {
@@ -52,13 +81,30 @@ func NewMockDynamicConfigurationGettableWithType[T any](
 	}
 }
 
-func (gettable *MockDynamicConfigurationGettableWithType[T]) Register(path []string, callback any) error {
+// The returned subscription does nothing when closed.
+func (gettable *MockDynamicConfigurationGettableWithType[T]) Register(
+	path []string,
+	callback any,
+) (*manager.Subscription, error) {
 	callbackFunc, ok := callback.(func(T) error)
 	if !ok {
-		return fmt.Errorf("invalid callback type: expected 'func(T) error', got %T", callback)
+		return nil, fmt.Errorf("invalid callback type: expected 'func(T) error', got %T", callback)
+	}
+
+	if err := gettable.register(path, callbackFunc); err != nil {
+		return nil, err
 	}
 
-	return gettable.register(path, callbackFunc)
+	return manager.NewSubscription(nil), nil
+}
+
+// The options are ignored by the mock.
+func (gettable *MockDynamicConfigurationGettableWithType[T]) RegisterWithOptions(
+	path []string,
+	callback any,
+	options manager.RegisterOptions,
+) (*manager.Subscription, error) {
+	return gettable.Register(path, callback)
 }
 
 func (gettable *MockDynamicConfigurationGettableWithType[T]) Get(path []string, out any) error {
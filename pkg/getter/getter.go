@@ -1,6 +1,14 @@
 package getter
 
-import "slices"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
 
 type DynamicConfigurationGetter struct {
 	gettable DynamicConfigurationGettable
@@ -21,7 +29,27 @@ func NewDynamicConfigurationGetterWithPrefix(
 	}
 }
 
-func (getter *DynamicConfigurationGetter) Register(callback any) error {
+// Register a callback on the getter's path. Closing the returned subscription removes the registration.
+func (getter *DynamicConfigurationGetter) Register(callback any) (*manager.Subscription, error) {
+	return getter.gettable.Register(getter.prefix, callback)
+}
+
+// Register a callback on the getter's path, with options that control how it is called.
+// If the gettable doesn't implement DynamicConfigurationGettableWithOptions, only the zero options are accepted, and
+// other options return ErrRegisterOptionsNotSupported.
+func (getter *DynamicConfigurationGetter) RegisterWithOptions(
+	callback any,
+	options manager.RegisterOptions,
+) (*manager.Subscription, error) {
+	gettable, ok := getter.gettable.(DynamicConfigurationGettableWithOptions)
+	if ok {
+		return gettable.RegisterWithOptions(getter.prefix, callback, options)
+	}
+
+	if !reflect.ValueOf(options).IsZero() {
+		return nil, fmt.Errorf("%w: gettable of type %T", ErrRegisterOptionsNotSupported, getter.gettable)
+	}
+
 	return getter.gettable.Register(getter.prefix, callback)
 }
 
@@ -35,3 +63,85 @@ func (getter *DynamicConfigurationGetter) Select(selection string) *DynamicConfi
 		prefix:   append(slices.Clone(getter.prefix), selection),
 	}
 }
+
+// Select an entry of a map by its key, or an element of a slice by the value of its key field.
+func (getter *DynamicConfigurationGetter) Key(key string) *DynamicConfigurationGetter {
+	return getter.Select(key)
+}
+
+// Select all of the entries of a map, or all of the elements of a slice or an array. Callbacks registered on the
+// returned getter are called per entry, see RegisterEntries and RegisterEntryEvents.
+func (getter *DynamicConfigurationGetter) All() *DynamicConfigurationGetter {
+	return getter.Select("*")
+}
+
+// Select an element of a slice or an array by its index.
+func (getter *DynamicConfigurationGetter) Index(index int) *DynamicConfigurationGetter {
+	return getter.Select(strconv.Itoa(index))
+}
+
+// Register a typed callback on the getter's path.
+// This is the same as DynamicConfigurationGetter.Register, except that the callback's signature is checked at compile
+// time.
+func Register[T any](getter *DynamicConfigurationGetter, callback func(T) error) (*manager.Subscription, error) {
+	return getter.Register(callback)
+}
+
+// Register a typed context-aware callback on the getter's path.
+// See Register.
+func RegisterContext[T any](
+	getter *DynamicConfigurationGetter,
+	callback func(context.Context, T) error,
+) (*manager.Subscription, error) {
+	return getter.Register(callback)
+}
+
+// Register a typed callback on the getter's path, which receives both the old and the new configuration.
+func RegisterChanges[T any](
+	getter *DynamicConfigurationGetter,
+	callback func(old T, new T) error,
+) (*manager.Subscription, error) {
+	return getter.Register(callback)
+}
+
+// Register a typed callback on the getter's wildcard path, called for each entry that is added or modified.
+func RegisterEntries[T any](
+	getter *DynamicConfigurationGetter,
+	callback func(key string, configuration T) error,
+) (*manager.Subscription, error) {
+	return getter.Register(callback)
+}
+
+// Register a typed callback on the getter's wildcard path, called for each entry that is added, modified or removed.
+func RegisterEntryEvents[T any](
+	getter *DynamicConfigurationGetter,
+	callback func(key string, event manager.EntryEvent, configuration T) error,
+) (*manager.Subscription, error) {
+	return getter.Register(callback)
+}
+
+// Watch the configuration under the getter's path through a channel, instead of registering a callback.
+// See manager.Watch.
+func Watch[T any](ctx context.Context, getter *DynamicConfigurationGetter) (<-chan T, error) {
+	return manager.Watch[T](ctx, getter.gettable, getter.prefix)
+}
+
+// Get the current value of the getter's path as the given type.
+// This is the same as DynamicConfigurationGetter.Get, except that no out parameter is needed.
+func Get[T any](getter *DynamicConfigurationGetter) (T, error) {
+	var out T
+	if err := getter.Get(&out); err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
+
+// Register a typed two-phase configurable on the getter's path.
+// See manager.TwoPhaseConfigurable for the two-phase protocol.
+func RegisterConfigurable[T any](
+	getter *DynamicConfigurationGetter,
+	configurable manager.TwoPhaseConfigurable[T],
+) (*manager.Subscription, error) {
+	return getter.Register(configurable)
+}
@@ -1,6 +1,7 @@
 package getter_test
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
@@ -45,18 +46,18 @@ func TestGetterWithManager(t *testing.T) {
 		return nil
 	}
 
-	if err := firstBGetter.Register(callbackFirstA); err == nil ||
+	if _, err := firstBGetter.Register(callbackFirstA); err == nil ||
 		!errors.Is(err, manager.ErrBadCallback) {
 		t.Fatalf("wrong error when registering bad callback: %v", err)
 	}
 
-	if err := secondGetter.Register(callbackSecond); err != nil {
+	if _, err := secondGetter.Register(callbackSecond); err != nil {
 		t.Fatalf("failed to register callback on Second configuration: %v", err)
 	}
-	if err := firstAGetter.Register(callbackFirstA); err != nil {
+	if _, err := firstAGetter.Register(callbackFirstA); err != nil {
 		t.Fatalf("failed to register callback on FirstA configuration: %v", err)
 	}
-	if err := firstBGetter.Register(callbackFirstB); err != nil {
+	if _, err := firstBGetter.Register(callbackFirstB); err != nil {
 		t.Fatalf("failed to register callback on FirstB configuration: %v", err)
 	}
 
@@ -94,7 +95,7 @@ func TestGetterOnTopLevel(t *testing.T) {
 		return nil
 	}
 
-	if err := topLevelGetter.Register(callback); err != nil {
+	if _, err := topLevelGetter.Register(callback); err != nil {
 		t.Fatalf("failed to register callback: %v", err)
 	}
 
@@ -146,7 +147,7 @@ func TestGetterMockWithType(t *testing.T) {
 		t.Fatalf("after updating configuration using get, expected %#v but got %#v", cfgA1, outA1)
 	}
 
-	if err := getter.Register(callbackA2); err != nil {
+	if _, err := getter.Register(callbackA2); err != nil {
 		t.Fatalf("failed to register A2: %v", err)
 	}
 	if !reflect.DeepEqual(outA2, cfgA2) {
@@ -163,3 +164,296 @@ func TestGetterMockWithType(t *testing.T) {
 		t.Fatalf("after updating configuration using callback 2nd time, expected %#v but got %#v", cfgA2_2, outA2)
 	}
 }
+
+func TestTypedGetter(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels](
+		"testTypedGetter",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+	mockConfiguration := testutils.RandomMockConfigurationWithTwoDepthLevels()
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has two depth levels: %v", err)
+	}
+
+	firstAGetter := getter.NewDynamicConfigurationGetter(mgr).Select("First").Select("A")
+
+	var copyConfiguration testutils.MockConfigurationA
+	if _, err := getter.Register(firstAGetter, func(cfg testutils.MockConfigurationA) error {
+		copyConfiguration = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback on FirstA configuration: %v", err)
+	}
+
+	mockConfiguration.First.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if !reflect.DeepEqual(copyConfiguration, mockConfiguration.First.A) {
+		t.Fatalf(
+			"after updating configuration, expected %#v but got %#v",
+			mockConfiguration.First.A,
+			copyConfiguration,
+		)
+	}
+
+	gotA, err := getter.Get[testutils.MockConfigurationA](firstAGetter)
+	if err != nil {
+		t.Fatalf("failed to get FirstA configuration: %v", err)
+	}
+	if !reflect.DeepEqual(gotA, mockConfiguration.First.A) {
+		t.Fatalf("after getting configuration, expected %#v but got %#v", mockConfiguration.First.A, gotA)
+	}
+}
+
+func TestGetterOnCollectionEntries(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections](
+		"testGetterOnCollectionEntries",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has collections: %v", err)
+	}
+
+	topLevelGetter := getter.NewDynamicConfigurationGetter(mgr)
+
+	acme, err := getter.Get[testutils.MockConfigurationA](topLevelGetter.Select("Tenants").Key("acme"))
+	if err != nil {
+		t.Fatalf("failed to get map entry: %v", err)
+	}
+	if !reflect.DeepEqual(acme, mockConfiguration.Tenants["acme"]) {
+		t.Fatalf("after getting map entry, expected %#v but got %#v", mockConfiguration.Tenants["acme"], acme)
+	}
+
+	shard, err := getter.Get[testutils.MockConfigurationB](topLevelGetter.Select("Shards").Index(1))
+	if err != nil {
+		t.Fatalf("failed to get slice element: %v", err)
+	}
+	if !reflect.DeepEqual(shard, mockConfiguration.Shards[1]) {
+		t.Fatalf("after getting slice element, expected %#v but got %#v", mockConfiguration.Shards[1], shard)
+	}
+}
+
+func TestGetterOnAllEntries(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections](
+		"testGetterOnAllEntries",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has collections: %v", err)
+	}
+
+	tenantsGetter := getter.NewDynamicConfigurationGetter(mgr).Select("Tenants").All()
+
+	tenants := make(map[string]testutils.MockConfigurationA)
+	if _, err := getter.RegisterEntries(tenantsGetter, func(key string, cfg testutils.MockConfigurationA) error {
+		tenants[key] = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on all entries: %v", err)
+	}
+	if !reflect.DeepEqual(tenants, mockConfiguration.Tenants) {
+		t.Fatalf("after registering on all entries, expected %#v but got %#v", mockConfiguration.Tenants, tenants)
+	}
+
+	removed := make([]string, 0)
+	if _, err := getter.RegisterEntryEvents(
+		tenantsGetter,
+		func(key string, event manager.EntryEvent, cfg testutils.MockConfigurationA) error {
+			if event == manager.EntryRemoved {
+				removed = append(removed, key)
+			}
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register on all entry events: %v", err)
+	}
+
+	mockConfiguration = testutils.MockConfigurationWithCollections{}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"acme", "initech"}) {
+		t.Fatalf("after removing all entries, expected removal of acme and initech but got %v", removed)
+	}
+}
+
+func TestGetterWatch(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels]("testGetterWatch")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+	mockConfiguration := testutils.RandomMockConfigurationWithTwoDepthLevels()
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nextLevelGetter := getter.NewDynamicConfigurationGetter(mgr).Select("First").Select("A")
+	updates, err := getter.Watch[testutils.MockConfigurationA](ctx, nextLevelGetter)
+	if err != nil {
+		t.Fatalf("failed to watch getter: %v", err)
+	}
+
+	if cfg := <-updates; cfg != mockConfiguration.First.A {
+		t.Fatalf("expected current configuration %#v but got %#v", mockConfiguration.First.A, cfg)
+	}
+
+	mockConfiguration.First.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	if cfg := <-updates; cfg != mockConfiguration.First.A {
+		t.Fatalf("expected updated configuration %#v but got %#v", mockConfiguration.First.A, cfg)
+	}
+}
+
+func TestBind(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels]("testBind")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+	mockConfiguration := testutils.RandomMockConfigurationWithTwoDepthLevels()
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %v", err)
+	}
+
+	value, err := getter.Bind[testutils.MockConfigurationA](
+		getter.NewDynamicConfigurationGetter(mgr).Select("First").Select("A"),
+	)
+	if err != nil {
+		t.Fatalf("failed to bind value: %v", err)
+	}
+
+	if cfg := value.Load(); cfg != mockConfiguration.First.A {
+		t.Fatalf("after binding, expected %#v but got %#v", mockConfiguration.First.A, cfg)
+	}
+
+	mockConfiguration.First.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	if cfg := value.Load(); cfg != mockConfiguration.First.A {
+		t.Fatalf("after updating, expected %#v but got %#v", mockConfiguration.First.A, cfg)
+	}
+
+	if err := value.Close(); err != nil {
+		t.Fatalf("failed to close value: %v", err)
+	}
+
+	lastConfiguration := mockConfiguration.First.A
+	mockConfiguration.First.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	if cfg := value.Load(); cfg != lastConfiguration {
+		t.Fatalf("after closing, expected %#v but got %#v", lastConfiguration, cfg)
+	}
+
+	if _, err := getter.Bind[testutils.MockConfigurationB](
+		getter.NewDynamicConfigurationGetter(mgr).Select("First").Select("A"),
+	); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when binding value of wrong type: %v", err)
+	}
+}
+
+func TestBindDoesNotHoldRejectedConfiguration(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels](
+		"testBindRejected",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+	mockConfiguration := testutils.RandomMockConfigurationWithTwoDepthLevels()
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %v", err)
+	}
+
+	value, err := getter.Bind[testutils.MockConfigurationA](
+		getter.NewDynamicConfigurationGetter(mgr).Select("First").Select("A"),
+	)
+	if err != nil {
+		t.Fatalf("failed to bind value: %v", err)
+	}
+
+	// The later registration rejects the update after the value's registration would have been called.
+	initialA := mockConfiguration.First.A
+	initialB := mockConfiguration.First.B
+	var loadedDuringUpdate testutils.MockConfigurationA
+	if _, err := getter.Register(
+		getter.NewDynamicConfigurationGetter(mgr).Select("First").Select("B"),
+		func(cfg testutils.MockConfigurationB) error {
+			loadedDuringUpdate = value.Load()
+			if cfg != initialB {
+				return errors.ErrUnsupported
+			}
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register rejecting callback: %v", err)
+	}
+
+	mockConfiguration.First.A.Value += "bla"
+	mockConfiguration.First.B.Value = !mockConfiguration.First.B.Value
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %v", err)
+	}
+
+	if loadedDuringUpdate != initialA {
+		t.Fatalf("during rejected update, expected %#v but got %#v", initialA, loadedDuringUpdate)
+	}
+	if cfg := value.Load(); cfg != initialA {
+		t.Fatalf("after rejected update, expected %#v but got %#v", initialA, cfg)
+	}
+}
+
+func TestRegisterWithOptionsOnGettableWithoutOptions(t *testing.T) {
+	registered := 0
+	mock := getter.NewMockDynamicConfigurationGettable(
+		func(path []string, callback any) error {
+			registered++
+			return nil
+		},
+		func(path []string, out any) error { return nil },
+	)
+	// Only the methods of the base interface are promoted, as for gettables implemented before options existed.
+	gettable := struct {
+		getter.DynamicConfigurationGettable
+	}{mock}
+	dynamicConfigurationGetter := getter.NewDynamicConfigurationGetter(gettable)
+
+	callback := func(cfg testutils.MockConfigurationA) error { return nil }
+	if _, err := dynamicConfigurationGetter.RegisterWithOptions(callback, manager.RegisterOptions{}); err != nil {
+		t.Fatalf("failed to register with zero options: %#v", err)
+	}
+	if registered != 1 {
+		t.Fatalf("expected registration with zero options to fall back to Register, got %d registrations", registered)
+	}
+
+	if _, err := dynamicConfigurationGetter.RegisterWithOptions(
+		callback,
+		manager.RegisterOptions{Priority: 1},
+	); !errors.Is(err, getter.ErrRegisterOptionsNotSupported) {
+		t.Fatalf("wrong error when registering with unsupported options: %#v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package getter
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+// A live handle to the configuration under a getter's path, which can be read on hot paths.
+// The handle keeps a registration of its own, which is called after each configuration update is applied (see
+// manager.RegisterOptions.AfterCommit), so it never holds a configuration which is then rejected. If the getter's
+// gettable doesn't support register options, the handle is registered like any other callback instead, so it may
+// briefly hold a rejected configuration, until it's restored.
+type Value[T any] struct {
+	current      atomic.Pointer[T]
+	subscription *manager.Subscription
+}
+
+// Bind a live handle to the configuration under the getter's path. Close the handle to remove its registration.
+func Bind[T any](getter *DynamicConfigurationGetter) (*Value[T], error) {
+	value := &Value[T]{}
+	value.current.Store(new(T))
+
+	store := func(configuration T) error {
+		value.current.Store(&configuration)
+		return nil
+	}
+
+	subscription, err := getter.RegisterWithOptions(store, manager.RegisterOptions{AfterCommit: true})
+	if errors.Is(err, ErrRegisterOptionsNotSupported) {
+		subscription, err = Register(getter, store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	value.subscription = subscription
+
+	return value, nil
+}
+
+// Get the latest configuration. This never blocks.
+func (value *Value[T]) Load() T {
+	return *value.current.Load()
+}
+
+// Remove the handle's registration. The handle keeps returning the last configuration it got.
+func (value *Value[T]) Close() error {
+	return value.subscription.Close()
+}
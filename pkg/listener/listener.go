@@ -1,11 +1,15 @@
 package listener
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/groundcover-com/dynconf/pkg/manager"
 	metrics_factory "github.com/groundcover-com/metrics/pkg/factory"
 	"github.com/spf13/viper"
 )
@@ -22,12 +26,29 @@ type DynamicConfigurable[Configuration any] interface {
 	OnConfigurationUpdate(newConfiguration Configuration) error
 }
 
+// A dynamic configurable which also accepts the context of the update. If the listener's configurable implements it,
+// updates are labeled with the file they were read from as their source (see manager.WithSource).
+type DynamicContextConfigurable[Configuration any] interface {
+	OnConfigurationUpdateContext(ctx context.Context, newConfiguration Configuration) error
+}
+
+// A dynamic configurable which can check whether it would accept a configuration without applying it, such as the
+// manager. See ValidateFile.
+type DynamicConfigurationValidator[Configuration any] interface {
+	ValidateConfigurationUpdate(newConfiguration Configuration) error
+}
+
 type DynamicConfigurationListener[Configuration any] struct {
 	dynamicConfigurable DynamicConfigurable[Configuration]
 	options             Options
+	file                string
 
 	configuration Configuration
 	updateLock    sync.Mutex
+
+	// The timer of the quiet period after the last change of the file, see Options.Debounce.
+	debounceTimer *time.Timer
+	debounceLock  sync.Mutex
 }
 
 func NewDynamicConfigurationListener[Configuration any](
@@ -48,6 +69,7 @@ func NewDynamicConfigurationListener[Configuration any](
 	listener := &DynamicConfigurationListener[Configuration]{
 		options:             options,
 		dynamicConfigurable: dynamicConfigurable,
+		file:                file,
 	}
 
 	// To watch a configuration file with viper it has to exist when setting the watcher.
@@ -67,19 +89,80 @@ func NewDynamicConfigurationListener[Configuration any](
 		return nil, fmt.Errorf("failed to update initial dynamic configuration: %w", err)
 	}
 
-	vpr.WatchConfig()
-	vpr.OnConfigChange(func(e fsnotify.Event) {
+	onConfigChange := func(vpr *viper.Viper) {
+		if options.Callbacks.OnConfigurationUpdateStarted != nil {
+			options.Callbacks.OnConfigurationUpdateStarted(file)
+		}
+
+		started := time.Now()
 		if err := listener.update(vpr); err != nil {
 			metricFailedToUpdateDynamicConfiguration.Inc()
 			if options.Callbacks.OnConfigurationUpdateFailure != nil {
 				options.Callbacks.OnConfigurationUpdateFailure(err)
 			}
+			return
+		}
+
+		if options.Callbacks.OnConfigurationUpdateSuccess != nil {
+			options.Callbacks.OnConfigurationUpdateSuccess(file, time.Since(started))
 		}
+	}
+
+	vpr.WatchConfig()
+	vpr.OnConfigChange(func(e fsnotify.Event) {
+		if options.Debounce <= 0 {
+			onConfigChange(vpr)
+			return
+		}
+
+		listener.debounce(func() {
+			// The watching viper re-reads the file in its own goroutine upon every change, so the debounced update
+			// reads the file through a viper of its own.
+			debouncedVpr := options.Viper.New()
+			debouncedVpr.SetConfigFile(file)
+			onConfigChange(debouncedVpr)
+		})
 	})
 
 	return listener, nil
 }
 
+// Load a configuration file the same way a listener with the same options does, and check whether the validator would
+// accept it, without applying it or watching the file. This allows checking a configuration file before it is pushed.
+func ValidateFile[Configuration any](
+	file string,
+	validator DynamicConfigurationValidator[Configuration],
+	options Options,
+) error {
+	vpr := options.Viper.New()
+	vpr.SetConfigFile(file)
+
+	configuration, err := load[Configuration](vpr, options)
+	if err != nil {
+		return err
+	}
+
+	if err := validator.ValidateConfigurationUpdate(configuration); err != nil {
+		return fmt.Errorf("configuration file %s is invalid: %w", file, err)
+	}
+
+	return nil
+}
+
+// Call the function once the quiet period passes without further calls to debounce, so that a burst of changes is
+// handled once.
+func (listener *DynamicConfigurationListener[Configuration]) debounce(f func()) {
+	listener.debounceLock.Lock()
+	defer listener.debounceLock.Unlock()
+
+	if listener.debounceTimer == nil {
+		listener.debounceTimer = time.AfterFunc(listener.options.Debounce, f)
+		return
+	}
+
+	listener.debounceTimer.Reset(listener.options.Debounce)
+}
+
 func (listener *DynamicConfigurationListener[Configuration]) GetConfiguration() Configuration {
 	return listener.configuration
 }
@@ -88,23 +171,54 @@ func (listener *DynamicConfigurationListener[Configuration]) update(vpr *viper.V
 	listener.updateLock.Lock()
 	defer listener.updateLock.Unlock()
 
-	if err := listener.options.BaseConfiguration.Init(vpr); err != nil {
-		return fmt.Errorf("failed to initiate base configuration: %w", err)
+	mergedConfig, err := load[Configuration](vpr, listener.options)
+	if err != nil {
+		// Configurations that are applied or rejected are audited by the manager, but this one never reaches it.
+		if listener.options.Audit != nil {
+			listener.options.Audit.FailedToLoad(listener.file, err)
+		}
+		return err
 	}
 
-	if err := vpr.MergeInConfig(); err != nil {
-		return fmt.Errorf("error performing configuration merge: %w", err)
+	// When the manager coalesces updates, a superseded configuration isn't a failure, as a later one is applied
+	// instead of it. It isn't applied either, though, so the listener keeps its previous configuration.
+	err = listener.notify(mergedConfig)
+	if errors.Is(err, manager.ErrUpdateSuperseded) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update configuration: %w", err)
 	}
 
+	listener.configuration = mergedConfig
+	return nil
+}
+
+// Read the configuration file which the viper is set to, and merge it onto the base configuration.
+func load[Configuration any](vpr *viper.Viper, options Options) (Configuration, error) {
 	var mergedConfig Configuration
+
+	if err := options.BaseConfiguration.Init(vpr); err != nil {
+		return mergedConfig, fmt.Errorf("failed to initiate base configuration: %w", err)
+	}
+
+	if err := vpr.MergeInConfig(); err != nil {
+		return mergedConfig, fmt.Errorf("error performing configuration merge: %w", err)
+	}
+
 	if err := vpr.Unmarshal(&mergedConfig); err != nil {
-		return fmt.Errorf("failed to unmarshal merged configuration: %w", err)
+		return mergedConfig, fmt.Errorf("failed to unmarshal merged configuration: %w", err)
 	}
 
-	if err := listener.dynamicConfigurable.OnConfigurationUpdate(mergedConfig); err != nil {
-		return fmt.Errorf("failed to update configuration: %w", err)
+	return mergedConfig, nil
+}
+
+func (listener *DynamicConfigurationListener[Configuration]) notify(newConfiguration Configuration) error {
+	contextConfigurable, ok := listener.dynamicConfigurable.(DynamicContextConfigurable[Configuration])
+	if !ok {
+		return listener.dynamicConfigurable.OnConfigurationUpdate(newConfiguration)
 	}
 
-	listener.configuration = mergedConfig
-	return nil
+	ctx := manager.WithSource(context.Background(), listener.file)
+	return contextConfigurable.OnConfigurationUpdateContext(ctx, newConfiguration)
 }
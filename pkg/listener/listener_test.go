@@ -0,0 +1,136 @@
+package listener_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/listener"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+type mockConfigurable struct {
+	lock    sync.Mutex
+	updates []testutils.MockConfigurationWithOneDepthLevel
+	err     error
+}
+
+func (configurable *mockConfigurable) OnConfigurationUpdate(
+	newConfiguration testutils.MockConfigurationWithOneDepthLevel,
+) error {
+	configurable.lock.Lock()
+	defer configurable.lock.Unlock()
+
+	configurable.updates = append(configurable.updates, newConfiguration)
+	return configurable.err
+}
+
+func (configurable *mockConfigurable) setError(err error) {
+	configurable.lock.Lock()
+	defer configurable.lock.Unlock()
+
+	configurable.err = err
+}
+
+func (configurable *mockConfigurable) getUpdates() []testutils.MockConfigurationWithOneDepthLevel {
+	configurable.lock.Lock()
+	defer configurable.lock.Unlock()
+
+	return configurable.updates
+}
+
+func writeConfigurationFile(t *testing.T, file string, value string) {
+	if err := os.WriteFile(file, []byte(fmt.Sprintf("a:\n  value: %s\n", value)), 0644); err != nil {
+		t.Fatalf("failed to write configuration file: %v", err)
+	}
+}
+
+// Start listening on a configuration file, returning a channel which receives the result of every update of it.
+func newListener(
+	t *testing.T,
+	configurable *mockConfigurable,
+	debounce time.Duration,
+) (string, *listener.DynamicConfigurationListener[testutils.MockConfigurationWithOneDepthLevel], chan error) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigurationFile(t, file, "initial")
+
+	results := make(chan error, 100)
+	dynamicListener, err := listener.NewDynamicConfigurationListener[testutils.MockConfigurationWithOneDepthLevel](
+		t.Name(),
+		file,
+		configurable,
+		listener.Options{
+			Viper:    listener.ViperOptions{ConfigType: "yaml"},
+			Debounce: debounce,
+			Callbacks: listener.Callbacks{
+				OnConfigurationUpdateSuccess: func(file string, duration time.Duration) { results <- nil },
+				OnConfigurationUpdateFailure: func(err error) { results <- err },
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate listener: %v", err)
+	}
+
+	return file, dynamicListener, results
+}
+
+func TestDebounce(t *testing.T) {
+	const debounce = 200 * time.Millisecond
+	configurable := &mockConfigurable{}
+	file, dynamicListener, results := newListener(t, configurable, debounce)
+
+	for i := 0; i < 5; i++ {
+		writeConfigurationFile(t, file, fmt.Sprintf("value%d", i))
+		time.Sleep(debounce / 10)
+	}
+
+	select {
+	case err := <-results:
+		if err != nil {
+			t.Fatalf("failed to update configuration: %v", err)
+		}
+	case <-time.After(10 * debounce):
+		t.Fatalf("configuration wasn't updated after the configuration file changed")
+	}
+
+	// Any further update would follow within the quiet period.
+	select {
+	case err := <-results:
+		t.Fatalf("expected a single update after the configuration file stopped changing, but got another: %v", err)
+	case <-time.After(2 * debounce):
+	}
+
+	updates := configurable.getUpdates()
+	if len(updates) != 2 || updates[0].A.Value != "initial" || updates[1].A.Value != "value4" {
+		t.Fatalf("expected the initial configuration and the last one written, but got %v", updates)
+	}
+	if cfg := dynamicListener.GetConfiguration(); cfg.A.Value != "value4" {
+		t.Fatalf("expected the last configuration written but got %v", cfg)
+	}
+}
+
+func TestSupersededUpdateIsNotFailure(t *testing.T) {
+	configurable := &mockConfigurable{}
+	file, dynamicListener, results := newListener(t, configurable, 100*time.Millisecond)
+
+	configurable.setError(manager.ErrUpdateSuperseded)
+	writeConfigurationFile(t, file, "superseded")
+
+	select {
+	case err := <-results:
+		if err != nil {
+			t.Fatalf("superseded update was reported as a failure: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("configuration wasn't updated after the configuration file changed")
+	}
+
+	if cfg := dynamicListener.GetConfiguration(); cfg.A.Value != "initial" {
+		t.Fatalf("expected the listener to keep its configuration after a superseded update, but got %v", cfg)
+	}
+}
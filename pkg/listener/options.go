@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/groundcover-com/dynconf/pkg/audit"
 	"github.com/spf13/viper"
 )
 
@@ -26,6 +28,14 @@ type Options struct {
 	// originates from (etc. a file or a string).
 	BaseConfiguration BaseConfigurationOptions
 	Callbacks         Callbacks
+	// The audit observer which records configuration updates. Set it as an observer of the manager as well: the manager
+	// records applied, rejected and superseded updates, and the listener records updates which fail before reaching the
+	// manager.
+	Audit *audit.Observer
+	// The quiet period after a change of the configuration file, before it is read. Editors and ConfigMap updates
+	// change the file several times per save, so every change restarts the quiet period, and the file is read once it
+	// stops changing. Zero means the file is read upon every change.
+	Debounce time.Duration
 }
 
 type BaseConfigurationOptions struct {
@@ -83,6 +93,12 @@ func (options *ViperOptions) New() *viper.Viper {
 	return vpr
 }
 
+// Callbacks which are called upon updates of the configuration file. Any of them may be nil.
 type Callbacks struct {
+	// Called when the configuration file changed, before it is read.
+	OnConfigurationUpdateStarted func(file string)
+	// Called when the configuration file was read and applied, or superseded by a later configuration update (see
+	// manager.ErrUpdateSuperseded), with the duration of the update.
+	OnConfigurationUpdateSuccess func(file string, duration time.Duration)
 	OnConfigurationUpdateFailure func(error)
 }
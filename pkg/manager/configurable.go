@@ -1,33 +1,387 @@
 package manager
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var (
+	contextType    = reflect.TypeOf((*context.Context)(nil)).Elem()
+	stringType     = reflect.TypeOf("")
+	boolType       = reflect.TypeOf(false)
+	entryEventType = reflect.TypeOf(EntryAdded)
+)
+
+// A module that accepts a configuration in two phases: first all of the affected modules validate the new
+// configuration, and only if all of them agree, it is applied. Such modules don't need to be restored when a
+// configuration update is rejected by another two-phase module.
+//
+// Registering a value that implements this interface, instead of a callback, opts into the two-phase protocol. The
+// value may also implement RollbackConfigurable.
+type TwoPhaseConfigurable[T any] interface {
+	// Return an error if the configuration is invalid. This must not change the module's state.
+	Validate(configuration T) error
+	// Apply a configuration that was previously validated.
+	Apply(configuration T)
+}
+
+// A two-phase module which is restored differently than applying the previous configuration.
+// If a configuration update is rejected after the module applied it, Rollback is called with the previous
+// configuration. Two-phase modules which don't implement this have Apply called with the previous configuration.
+type RollbackConfigurable[T any] interface {
+	Rollback(oldConfiguration T)
+}
+
 type registeredConfigurable struct {
+	path       []string
+	pathString string
+	pathKey    string
+	options    RegisterOptions
+	// Set for registrations on all entries of a map or a slice. The path then has a single wildcard element.
+	wildcard bool
+
 	configurable any
 	expectedType reflect.Type
-	callback     reflect.Value
+	// Set for callbacks.
+	callback      reflect.Value
+	callbackShape callbackShape
+	// Set for callbacks registered with a validation function, see RegisterOptions.Validate.
+	validator      reflect.Value
+	validatorShape callbackShape
+	// Set for two-phase configurables. The rollback method is optional.
+	validateMethod reflect.Value
+	applyMethod    reflect.Value
+	rollbackMethod reflect.Value
+
+	// The maximum duration of a single call, or zero if there is no limit.
+	timeout time.Duration
+	// How the configurations given to the configurable are isolated from the manager's configuration.
+	isolation Isolation
+	// The metrics of the manager, by which panics are counted.
+	metrics *DynamicConfigurationManagerMetrics
+
+	// Whether a call is in progress, which may be a call that timed out and still runs in the background. While it
+	// runs, further calls are rejected at once, and the latest restoration is deferred until it returns, so that a
+	// hung module neither blocks the manager nor piles up goroutines.
+	callsLock       sync.Mutex
+	calling         bool
+	deferredRestore func()
+
+	// Set once the registration is removed. It is checked before every call, as removal from the registered
+	// configurables may be delayed until the configuration update lock is available.
+	unsubscribed atomic.Bool
+}
+
+// The arguments which a callback receives before the configuration, in order.
+type callbackShape struct {
+	receivesContext bool
+	receivesKey     bool
+	receivesEvent   bool
+	receivesInitial bool
+	receivesOld     bool
+}
+
+func (configurable *registeredConfigurable) isTwoPhase() bool {
+	return configurable.validateMethod.IsValid()
+}
+
+// Whether the module can check a configuration without applying it, i.e. whether it's a two-phase configurable or a
+// callback registered with a validation function.
+func (configurable *registeredConfigurable) canValidate() bool {
+	return configurable.isTwoPhase() || configurable.validator.IsValid()
+}
+
+// Check whether the module allows the new configuration, without applying it.
+// Only modules which can validate a configuration are checked (see canValidate), so for other callbacks this does
+// nothing. Removed entries have no configuration to check.
+func (configurable *registeredConfigurable) validate(ctx context.Context, configurations pathConfigurations) error {
+	if !configurable.canValidate() || configurations.event == EntryRemoved {
+		return nil
+	}
+
+	castedCfgValue, err := configurable.cast(configurations.new)
+	if err != nil {
+		return err
+	}
+
+	return configurable.deliver(ctx, []reflect.Value{castedCfgValue}, func(ctx context.Context) error {
+		if configurable.isTwoPhase() {
+			return errorFromReturnValue(configurable.validateMethod.Call([]reflect.Value{castedCfgValue})[0])
+		}
+
+		args := make([]reflect.Value, 0, 3)
+		if configurable.validatorShape.receivesContext {
+			args = append(args, reflect.ValueOf(&ctx).Elem())
+		}
+		if configurable.validatorShape.receivesKey {
+			args = append(args, reflect.ValueOf(configurations.key))
+		}
+		args = append(args, castedCfgValue)
+
+		return errorFromReturnValue(configurable.validator.Call(args)[0])
+	})
+}
+
+// Apply the new configuration. For callbacks, this is also where the module may reject the configuration.
+func (configurable *registeredConfigurable) call(ctx context.Context, configurations pathConfigurations) error {
+	// Only callbacks which receive events can be told that the entry was removed.
+	if configurations.event == EntryRemoved && !configurable.callbackShape.receivesEvent {
+		return nil
+	}
+
+	return configurable.apply(ctx, configurations.key, configurations.event, configurations.old, configurations.new)
+}
+
+// Apply the old configuration, after the new configuration was applied and then rejected by another module.
+func (configurable *registeredConfigurable) restore(ctx context.Context, configurations pathConfigurations) error {
+	ctx = withRestoration(ctx)
+	if configurable.wildcard && configurable.callbackShape.receivesEvent {
+		switch configurations.event {
+		case EntryAdded:
+			return configurable.apply(ctx, configurations.key, EntryRemoved, configurations.new, nil)
+		case EntryRemoved:
+			return configurable.apply(ctx, configurations.key, EntryAdded, nil, configurations.old)
+		}
+	}
+
+	// Only callbacks which receive events are told about removed entries in the first place.
+	if configurations.event == EntryRemoved {
+		return nil
+	}
+
+	// Without a previous configuration, i.e. when the entry was added, the module is restored to the zero value,
+	// which stands for the entry not existing.
+	if !configurable.rollbackMethod.IsValid() {
+		return configurable.apply(ctx, configurations.key, EntryModified, configurations.new, configurations.old)
+	}
+
+	castedCfgValue, err := configurable.cast(configurations.old)
+	if err != nil {
+		return err
+	}
+
+	return configurable.deliver(ctx, []reflect.Value{castedCfgValue}, func(ctx context.Context) error {
+		configurable.rollbackMethod.Call([]reflect.Value{castedCfgValue})
+		return nil
+	})
+}
+
+// Apply a transition from the previous configuration to the current one. A nil configuration stands for the zero
+// value, when there is no previous configuration or when the entry was removed. Callbacks which receive the old and
+// new configurations are given both, and other callbacks are given the current configuration, or for removed entries,
+// their last configuration.
+func (configurable *registeredConfigurable) apply(
+	ctx context.Context,
+	key string,
+	event EntryEvent,
+	previous any,
+	current any,
+) error {
+	configuration := current
+	if event == EntryRemoved && !configurable.callbackShape.receivesOld {
+		configuration = previous
+	}
+
+	castedCfgValue, err := configurable.cast(configuration)
+	if err != nil {
+		return err
+	}
+
+	values := []reflect.Value{castedCfgValue}
+	var castedPreviousCfgValue reflect.Value
+	if configurable.callbackShape.receivesOld {
+		castedPreviousCfgValue, err = configurable.cast(previous)
+		if err != nil {
+			return err
+		}
+		values = append(values, castedPreviousCfgValue)
+	}
+
+	return configurable.deliver(ctx, values, func(ctx context.Context) error {
+		if configurable.isTwoPhase() {
+			configurable.applyMethod.Call([]reflect.Value{castedCfgValue})
+			return nil
+		}
+
+		args := make([]reflect.Value, 0, 6)
+		if configurable.callbackShape.receivesContext {
+			args = append(args, reflect.ValueOf(&ctx).Elem())
+		}
+		if configurable.callbackShape.receivesKey {
+			args = append(args, reflect.ValueOf(key))
+		}
+		if configurable.callbackShape.receivesEvent {
+			args = append(args, reflect.ValueOf(event))
+		}
+		if configurable.callbackShape.receivesInitial {
+			args = append(args, reflect.ValueOf(IsInitialDelivery(ctx)))
+		}
+		if configurable.callbackShape.receivesOld {
+			args = append(args, castedPreviousCfgValue)
+		}
+		args = append(args, castedCfgValue)
+
+		return errorFromReturnValue(configurable.callback.Call(args)[0])
+	})
+}
+
+// Run the given function with the configurable's timeout applied to the context.
+// If the context is done before the function returns, ErrCallbackTimeout is returned. The function keeps running in
+// the background in that case, but its result is ignored. Until it returns, further calls are rejected at once with
+// ErrCallbackTimeout, except for restorations, which also return ErrCallbackTimeout but take place once it returns.
+// If the function panics, the panic is recovered and returned as a *PanicError.
+func (configurable *registeredConfigurable) invoke(ctx context.Context, f func(ctx context.Context) error) error {
+	f = configurable.recovering(configurable.unlessUnsubscribed(f))
+	deferredCtx := context.WithoutCancel(ctx)
+	if configurable.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, configurable.timeout)
+		defer cancel()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCallbackTimeout, err)
+	}
+
+	if err := configurable.startCall(isRestoration(ctx), func() { _ = f(deferredCtx) }); err != nil {
+		return err
+	}
+	call := f
+	f = func(ctx context.Context) error {
+		defer configurable.finishCall()
+		return call(ctx)
+	}
+
+	// Without a deadline or cancellation there is nothing to wait for, so the function is called directly.
+	if ctx.Done() == nil {
+		return f(ctx)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- f(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrCallbackTimeout, ctx.Err())
+	}
 }
 
-func (configurable *registeredConfigurable) call(configuration any) error {
+// Mark a call as in progress, unless the previous call, which timed out, is still running. In that case the call is
+// rejected, and if it's a restoration, the given function is deferred until the previous call returns, replacing any
+// restoration deferred before it.
+func (configurable *registeredConfigurable) startCall(restoration bool, restore func()) error {
+	configurable.callsLock.Lock()
+	defer configurable.callsLock.Unlock()
+
+	if !configurable.calling {
+		configurable.calling = true
+		return nil
+	}
+
+	if restoration {
+		configurable.deferredRestore = restore
+		return fmt.Errorf(
+			"%w: the previous call hasn't returned yet, so the module is restored once it returns",
+			ErrCallbackTimeout,
+		)
+	}
+
+	return fmt.Errorf("%w: the previous call hasn't returned yet", ErrCallbackTimeout)
+}
+
+// Mark the call in progress as done, after running the restorations deferred while it ran.
+func (configurable *registeredConfigurable) finishCall() {
+	for {
+		configurable.callsLock.Lock()
+		restore := configurable.deferredRestore
+		configurable.deferredRestore = nil
+		if restore == nil {
+			configurable.calling = false
+			configurable.callsLock.Unlock()
+			return
+		}
+		configurable.callsLock.Unlock()
+
+		restore()
+	}
+}
+
+// Wrap the function so that it isn't called if the registration was removed in the meantime. The registration may be
+// removed from another goroutine during a configuration update, after the update checked it, so it's checked again
+// right before the call.
+func (configurable *registeredConfigurable) unlessUnsubscribed(
+	f func(ctx context.Context) error,
+) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if configurable.unsubscribed.Load() {
+			return nil
+		}
+
+		return f(ctx)
+	}
+}
+
+// Wrap the function so that if it panics, the panic is recovered and returned as a *PanicError.
+// The recovery has to happen in the goroutine which runs the function, which may not be the caller's goroutine.
+func (configurable *registeredConfigurable) recovering(
+	f func(ctx context.Context) error,
+) func(ctx context.Context) error {
+	return func(ctx context.Context) (err error) {
+		if panicErr := configurable.metrics.recovered(func() { err = f(ctx) }); panicErr != nil {
+			return panicErr
+		}
+
+		return err
+	}
+}
+
+// Call the function, returning a panic of it as a *PanicError, which is counted by the callback panics metric.
+// Functions given by users, such as callbacks, comparators and observers, are called this way, so that they can't
+// crash the process during configuration updates.
+func (metrics *DynamicConfigurationManagerMetrics) recovered(f func()) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			metrics.callbackPanicked.Inc()
+			err = &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+
+	f()
+	return nil
+}
+
+func (configurable *registeredConfigurable) cast(configuration any) (reflect.Value, error) {
 	castedCfg := reflect.New(configurable.expectedType).Interface()
 
 	castedCfgValue := reflect.ValueOf(castedCfg).Elem()
 	newCfgValue := reflect.ValueOf(configuration)
+	if !newCfgValue.IsValid() { // a nil interface leaf, whose zero value is already set
+		return castedCfgValue, nil
+	}
 	newCfgValueType := newCfgValue.Type()
 
 	if !newCfgValueType.AssignableTo(configurable.expectedType) {
-		return fmt.Errorf(
+		return reflect.Value{}, fmt.Errorf(
 			"configuration value of type %s isn't assignable to expected type %s",
 			newCfgValueType.String(),
 			configurable.expectedType.String(),
 		)
 	}
 
-	castedCfgValue.Set(newCfgValue)
-	returnValue := configurable.callback.Call([]reflect.Value{castedCfgValue})[0]
+	castedCfgValue.Set(isolate(configurable.isolation, newCfgValue))
+	return castedCfgValue, nil
+}
+
+func errorFromReturnValue(returnValue reflect.Value) error {
 	if returnValue.IsNil() {
 		return nil
 	}
@@ -0,0 +1,221 @@
+package manager_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+type mockTwoPhaseConfigurable struct {
+	name   string
+	calls  *[]string
+	reject func(testutils.MockConfigurationA) bool
+}
+
+func (configurable *mockTwoPhaseConfigurable) Validate(cfg testutils.MockConfigurationA) error {
+	*configurable.calls = append(*configurable.calls, configurable.name+":validate")
+	if configurable.reject != nil && configurable.reject(cfg) {
+		return errors.ErrUnsupported
+	}
+	return nil
+}
+
+func (configurable *mockTwoPhaseConfigurable) Apply(cfg testutils.MockConfigurationA) {
+	*configurable.calls = append(*configurable.calls, configurable.name+":apply")
+}
+
+type mockRollbackConfigurable struct {
+	mockTwoPhaseConfigurable
+}
+
+func (configurable *mockRollbackConfigurable) Rollback(cfg testutils.MockConfigurationA) {
+	*configurable.calls = append(*configurable.calls, configurable.name+":rollback")
+}
+
+func TestTwoPhaseConfigurableRejection(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testTwoPhaseRejection")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	first := &mockTwoPhaseConfigurable{name: "first", calls: &calls}
+	second := &mockTwoPhaseConfigurable{
+		name:   "second",
+		calls:  &calls,
+		reject: func(cfg testutils.MockConfigurationA) bool { return cfg.Value != mockConfiguration.Second.A.Value },
+	}
+
+	if _, err := manager.SubscribeConfigurable(
+		mgr,
+		[]string{"First", "A"},
+		manager.TwoPhaseConfigurable[testutils.MockConfigurationA](first),
+	); err != nil {
+		t.Fatalf("failed to register first: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "A"}, second); err != nil {
+		t.Fatalf("failed to register second: %#v", err)
+	}
+
+	expected := []string{"first:validate", "first:apply", "second:validate", "second:apply"}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("after registering, got calls %v (expected %v)", calls, expected)
+	}
+
+	calls = calls[:0]
+	newConfiguration := mockConfiguration
+	newConfiguration.First.A.Value += "bla"
+	newConfiguration.Second.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	expected = []string{"first:validate", "second:validate"}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("after rejected update, got calls %v (expected %v)", calls, expected)
+	}
+}
+
+func TestTwoPhaseConfigurableWithCallbackRejection(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testTwoPhaseWithCallback")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	withRollback := &mockRollbackConfigurable{mockTwoPhaseConfigurable{name: "rollback", calls: &calls}}
+	withoutRollback := &mockTwoPhaseConfigurable{name: "apply", calls: &calls}
+
+	if _, err := mgr.Register([]string{"First", "A"}, withRollback); err != nil {
+		t.Fatalf("failed to register configurable with rollback: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "A"}, withoutRollback); err != nil {
+		t.Fatalf("failed to register configurable without rollback: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"First", "B"}, func(cfg testutils.MockConfigurationB) error {
+		if cfg.Value != mockConfiguration.First.B.Value {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	calls = calls[:0]
+	newConfiguration := mockConfiguration
+	newConfiguration.First.A.Value += "bla"
+	newConfiguration.Second.A.Value += "bla"
+	newConfiguration.First.B.Value = !newConfiguration.First.B.Value
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	expected := []string{
+		"rollback:validate",
+		"apply:validate",
+		"rollback:apply",
+		"apply:apply",
+		"apply:apply",
+		"rollback:rollback",
+	}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("after rejected update, got calls %v (expected %v)", calls, expected)
+	}
+}
+
+func TestRegisterBadTwoPhaseConfigurable(t *testing.T) {
+	mgr, _, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testBadTwoPhase")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	if _, err := mgr.Register([]string{"First", "B"}, &mockTwoPhaseConfigurable{calls: &calls}); !errors.Is(
+		err,
+		manager.ErrBadCallback,
+	) {
+		t.Fatalf("wrong error when registering configurable of the wrong type: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"First", "A"}, struct{}{}); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering value without methods: %#v", err)
+	}
+}
+
+func TestCallbackPanicRecovered(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testCallbackPanicRecovered")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]testutils.MockConfigurationA, 0)
+	if _, err := mgr.Register([]string{"First", "A"}, func(cfg testutils.MockConfigurationA) error {
+		calls = append(calls, cfg)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	initialB := mockConfiguration.First.B
+	if _, err := mgr.Register([]string{"First", "B"}, func(cfg testutils.MockConfigurationB) error {
+		if cfg != initialB {
+			panic("bad configuration")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	errBadSecond := errors.New("bad second configuration")
+	initialSecond := mockConfiguration.Second
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"Second"},
+		func(cfg testutils.MockConfigurationWithOneDepthLevel) error {
+			if cfg != initialSecond {
+				panic(errBadSecond)
+			}
+			return nil
+		},
+		manager.RegisterOptions{Timeout: time.Minute},
+	); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	newConfiguration := mockConfiguration
+	newConfiguration.First.A.Value += "bla"
+	newConfiguration.First.B.Value = !newConfiguration.First.B.Value
+	err = mgr.OnConfigurationUpdate(newConfiguration)
+
+	var updateError *manager.UpdateError
+	var panicError *manager.PanicError
+	if !errors.As(err, &updateError) || !errors.As(err, &panicError) || !errors.Is(err, manager.ErrCallbackPanicked) {
+		t.Fatalf("wrong error when a callback panics: %#v", err)
+	}
+	if updateError.Path != "First.B" || panicError.Value != "bad configuration" || len(panicError.Stack) == 0 {
+		t.Fatalf("wrong details of panic: %#v, %#v", updateError, panicError)
+	}
+	if panicError.Error() != "callback panicked: bad configuration" {
+		t.Fatalf("wrong message of panic: %s", panicError.Error())
+	}
+
+	expectedCalls := []testutils.MockConfigurationA{mockConfiguration.First.A, newConfiguration.First.A,
+		mockConfiguration.First.A}
+	if !slices.Equal(calls, expectedCalls) {
+		t.Fatalf("expected calls %v after panic, but got %v", expectedCalls, calls)
+	}
+	if cfg := mgr.Snapshot(); cfg != mockConfiguration {
+		t.Fatalf("expected configuration %v after panic, but got %v", mockConfiguration, cfg)
+	}
+
+	// Callbacks with a timeout run in their own goroutine, where panics are recovered as well.
+	newConfiguration = mockConfiguration
+	newConfiguration.Second.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, errBadSecond) ||
+		!errors.Is(err, manager.ErrCallbackPanicked) {
+		t.Fatalf("wrong error when a callback with a timeout panics: %#v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package manager
+
+import "context"
+
+type initialDeliveryContextKey struct{}
+
+type sourceContextKey struct{}
+
+type restorationContextKey struct{}
+
+// Report whether the callback is called by Register with the configuration at the time of registration, rather than
+// by a configuration update. Callbacks which receive the old and new configurations get a zero old configuration on
+// the initial delivery, which this tells apart from an update whose old configuration happens to be zero.
+func IsInitialDelivery(ctx context.Context) bool {
+	initial, _ := ctx.Value(initialDeliveryContextKey{}).(bool)
+	return initial
+}
+
+func withInitialDelivery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, initialDeliveryContextKey{}, true)
+}
+
+// Mark the call as a restoration of the module's previous configuration, which is deferred rather than rejected if
+// the module's previous call hasn't returned yet.
+func withRestoration(ctx context.Context) context.Context {
+	return context.WithValue(ctx, restorationContextKey{}, true)
+}
+
+func isRestoration(ctx context.Context) bool {
+	restoration, _ := ctx.Value(restorationContextKey{}).(bool)
+	return restoration
+}
+
+// Label the configuration update with its source, such as the file it was read from or the API it was given by.
+// The source is recorded in the configuration history, and can be read by callbacks with SourceFromContext.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceContextKey{}, source)
+}
+
+// Get the source which the configuration update was labeled with, or an empty string if it wasn't labeled.
+func SourceFromContext(ctx context.Context) string {
+	source, _ := ctx.Value(sourceContextKey{}).(string)
+	return source
+}
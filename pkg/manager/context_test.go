@@ -0,0 +1,290 @@
+package manager_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+type contextKey struct{}
+
+func TestContextAwareCallback(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testContextAwareCallback")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var copyConfiguration testutils.MockConfigurationA
+	var gotValue any
+	if _, err := manager.SubscribeContext(
+		mgr,
+		[]string{"A"},
+		func(ctx context.Context, cfg testutils.MockConfigurationA) error {
+			gotValue = ctx.Value(contextKey{})
+			copyConfiguration = cfg
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register context-aware callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	if err := mgr.OnConfigurationUpdateContext(ctx, mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if gotValue != "value" {
+		t.Fatalf("callback got context value %v (expected %v)", gotValue, "value")
+	}
+
+	if !reflect.DeepEqual(copyConfiguration, mockConfiguration.A) {
+		t.Fatalf("after updating configuration, expected %#v but got %#v", mockConfiguration.A, copyConfiguration)
+	}
+
+	if _, err := mgr.Register([]string{"A"}, func(ctx string, cfg testutils.MockConfigurationA) error {
+		return nil
+	}); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering callback whose first argument isn't a context: %#v", err)
+	}
+}
+
+func TestCallbackTimeout(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testCallbackTimeout",
+		manager.Options{CallbackTimeout: time.Hour},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	origConfiguration := mockConfiguration
+
+	var copyConfiguration testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		copyConfiguration = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback A: %#v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"B"},
+		func(ctx context.Context, cfg testutils.MockConfigurationB) error {
+			if cfg == origConfiguration.B {
+				return nil
+			}
+			<-release
+			return nil
+		},
+		manager.RegisterOptions{Timeout: 10 * time.Millisecond},
+	); err != nil {
+		t.Fatalf("failed to register hanging callback B: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	err = mgr.OnConfigurationUpdate(mockConfiguration)
+	if !errors.Is(err, manager.ErrCallbackTimeout) {
+		t.Fatalf("wrong error when callback times out: %#v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error when callback times out doesn't wrap the context error: %#v", err)
+	}
+
+	if !reflect.DeepEqual(copyConfiguration, origConfiguration.A) {
+		t.Fatalf(
+			"after callback timed out, expected restoration to original %#v but got %#v",
+			origConfiguration.A,
+			copyConfiguration,
+		)
+	}
+
+	var gotA testutils.MockConfigurationA
+	if err := mgr.Get([]string{"A"}, &gotA); err != nil {
+		t.Fatalf("failed to get configuration after callback timed out: %#v", err)
+	}
+	if !reflect.DeepEqual(gotA, origConfiguration.A) {
+		t.Fatalf("after callback timed out, expected configuration %#v but got %#v", origConfiguration.A, gotA)
+	}
+}
+
+func TestTimedOutCallbackRestoredAfterApplyingLate(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testTimedOutCallbackAppliesLate")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	origConfiguration := mockConfiguration
+
+	release := make(chan struct{})
+	applied := make(chan testutils.MockConfigurationB, 3)
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"B"},
+		func(ctx context.Context, cfg testutils.MockConfigurationB) error {
+			if cfg != origConfiguration.B {
+				<-release
+			}
+			applied <- cfg
+			return nil
+		},
+		manager.RegisterOptions{Timeout: 10 * time.Millisecond},
+	); err != nil {
+		t.Fatalf("failed to register hanging callback B: %#v", err)
+	}
+	if initial := <-applied; initial != origConfiguration.B {
+		t.Fatalf("expected initial configuration %#v but got %#v", origConfiguration.B, initial)
+	}
+
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	err = mgr.OnConfigurationUpdate(mockConfiguration)
+	if !errors.Is(err, manager.ErrCallbackTimeout) {
+		t.Fatalf("wrong error when callback times out: %#v", err)
+	}
+
+	// The restoration can't take place while the timed-out call runs, so it's reported as timed out as well, but it's
+	// deferred until the timed-out call returns.
+	var updateError *manager.UpdateError
+	if !errors.As(err, &updateError) || !errors.Is(updateError.RestoreError(), manager.ErrCallbackTimeout) {
+		t.Fatalf("expected the restoration to time out waiting for the timed-out call: %#v", err)
+	}
+
+	close(release)
+	if late := <-applied; late != mockConfiguration.B {
+		t.Fatalf("expected the timed-out call to apply %#v but got %#v", mockConfiguration.B, late)
+	}
+	if restored := <-applied; restored != origConfiguration.B {
+		t.Fatalf(
+			"after the timed-out call applied late, expected restoration to %#v but got %#v",
+			origConfiguration.B,
+			restored,
+		)
+	}
+}
+
+func TestHungCallbackWithContextDeadline(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testHungCallbackDeadline")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	origConfiguration := mockConfiguration
+
+	release := make(chan struct{})
+	applied := make(chan testutils.MockConfigurationB, 3)
+	if _, err := manager.SubscribeContext(
+		mgr,
+		[]string{"B"},
+		func(ctx context.Context, cfg testutils.MockConfigurationB) error {
+			if cfg != origConfiguration.B {
+				<-release
+			}
+			applied <- cfg
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register hanging callback B: %#v", err)
+	}
+	if initial := <-applied; initial != origConfiguration.B {
+		t.Fatalf("expected initial configuration %#v but got %#v", origConfiguration.B, initial)
+	}
+
+	update := func() <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			result <- mgr.OnConfigurationUpdateContext(ctx, mockConfiguration)
+		}()
+		return result
+	}
+
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	select {
+	case err := <-update():
+		var updateError *manager.UpdateError
+		if !errors.As(err, &updateError) || !errors.Is(updateError.RestoreError(), manager.ErrCallbackTimeout) {
+			t.Fatalf("expected the update and the restoration to time out: %#v", err)
+		}
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatalf("update didn't return after its context's deadline while the callback hung")
+	}
+
+	// While the callback still hangs, further updates are rejected without waiting for it.
+	select {
+	case err := <-update():
+		if !errors.Is(err, manager.ErrCallbackTimeout) {
+			t.Fatalf("wrong error when updating while the callback hangs: %#v", err)
+		}
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatalf("update didn't return while the callback hung")
+	}
+
+	close(release)
+	if late := <-applied; late == origConfiguration.B {
+		t.Fatalf("expected the hung call to apply the updated configuration but got %#v", late)
+	}
+	if restored := <-applied; restored != origConfiguration.B {
+		t.Fatalf("after the hung call returned, expected restoration to %#v but got %#v", origConfiguration.B, restored)
+	}
+}
+
+func TestConfigurationUpdateWithCanceledContext(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testCanceledContext")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback A: %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdateContext(ctx, mockConfiguration); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wrong error when updating with a canceled context: %#v", err)
+	}
+}
+
+func TestIsInitialDelivery(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testIsInitialDelivery")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	initialDeliveries := make([]bool, 0)
+	if _, err := manager.SubscribeContext(
+		mgr,
+		[]string{"A"},
+		func(ctx context.Context, cfg testutils.MockConfigurationA) error {
+			initialDeliveries = append(initialDeliveries, manager.IsInitialDelivery(ctx))
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register context-aware callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if !reflect.DeepEqual(initialDeliveries, []bool{true, false}) {
+		t.Fatalf("expected only the first delivery to be initial, but got %v", initialDeliveries)
+	}
+}
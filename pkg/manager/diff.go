@@ -0,0 +1,273 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// A change of a single part of the configuration between two configurations.
+type Change struct {
+	// The dotted path of the changed part, made of the same path elements as the paths given to Register.
+	Path string
+	// Whether the part was added, modified or removed.
+	Kind EntryEvent
+	// The previous value of the part, or nil if it was added.
+	Old any
+	// The new value of the part, or nil if it was removed.
+	New any
+	// Whether the part is within a field tagged `dynconf:"secret"`, whose values must not be logged.
+	Secret bool
+}
+
+// Format the change, without the values of secret fields.
+func (change Change) String() string {
+	var oldValue, newValue any = RedactedValue, RedactedValue
+	if !change.Secret {
+		oldValue, newValue = Redact(change.Old), Redact(change.New)
+	}
+
+	switch change.Kind {
+	case EntryAdded:
+		return fmt.Sprintf("%s: added %v", change.Path, newValue)
+	case EntryRemoved:
+		return fmt.Sprintf("%s: removed %v", change.Path, oldValue)
+	default:
+		return fmt.Sprintf("%s: %v -> %v", change.Path, oldValue, newValue)
+	}
+}
+
+// Compute the changes between two configurations, down to their leaves.
+// The configurations are traversed like paths given to Register: fields of structs are named by their Go field names,
+// entries of maps by their keys, and elements of slices and arrays by their indices or key fields. Nil pointers are
+// treated as missing, so a pointer that became nil is reported as removed. Changes are ordered by field order, and by
+// key within maps, slices and arrays. A collection whose entries can't be keyed, e.g. elements which share a key, is
+// reported as a single modification. Changes within fields tagged `dynconf:"secret"` are marked as secret. Values are
+// compared as for change detection: fields tagged `dynconf:"ignore"` are skipped, NaN floats are equal to each other,
+// and funcs are equal if they are the same function.
+func Diff[Configuration any](oldConfiguration Configuration, newConfiguration Configuration) []Change {
+	differ := newDiffer(nil, newEquality(nil, nil, fieldMask{}, nil), nil)
+	differ.diff(nil, fieldScope{}, reflect.ValueOf(oldConfiguration), reflect.ValueOf(newConfiguration))
+	return differ.changes
+}
+
+// Compute the changes between two configurations, like Diff, with fields named as configured by the path tags option.
+// Values of types with a comparator are compared by it, and reported as a single change if they aren't equal.
+func (mgr *DynamicConfigurationManager[Configuration]) Diff(
+	oldConfiguration Configuration,
+	newConfiguration Configuration,
+) []Change {
+	changes, _ := mgr.diff(oldConfiguration, newConfiguration)
+	return changes
+}
+
+// Compute the changes between two configurations, like the Diff method, along with the changes of static fields,
+// which are also included in the changes.
+func (mgr *DynamicConfigurationManager[Configuration]) diff(
+	oldConfiguration Configuration,
+	newConfiguration Configuration,
+) ([]Change, []Change) {
+	differ := newDiffer(mgr.options.PathTags, mgr.newEquality(fieldMask{}), mgr.options.StaticPaths)
+	differ.diff(nil, fieldScope{}, reflect.ValueOf(oldConfiguration), reflect.ValueOf(newConfiguration))
+	return differ.changes, differ.staticChanges
+}
+
+// Get the changes made by the last applied configuration update. Rejected updates don't affect the result.
+// This doesn't wait for configuration updates in progress, so it may be called from callbacks and observers.
+func (mgr *DynamicConfigurationManager[Configuration]) LastChanges() []Change {
+	lastChanges := mgr.lastChanges.Load()
+	if lastChanges == nil {
+		return nil
+	}
+
+	return slices.Clone(*lastChanges)
+}
+
+type differ struct {
+	pathTags []string
+	equality *equality
+	// Paths whose changes are static, in addition to the changes of fields tagged `dynconf:"static"`.
+	staticPaths [][]string
+	changes     []Change
+	// The changes of static fields, see Options.StaticPaths.
+	staticChanges []Change
+}
+
+func newDiffer(pathTags []string, equality *equality, staticPaths [][]string) *differ {
+	return &differ{
+		pathTags:      pathTags,
+		equality:      equality,
+		staticPaths:   staticPaths,
+		changes:       make([]Change, 0),
+		staticChanges: make([]Change, 0),
+	}
+}
+
+// Whether the values being compared are within fields tagged as static or secret, which makes all of their changes so.
+type fieldScope struct {
+	static bool
+	secret bool
+}
+
+// Compute the changes between the values under the path. Each path element holds the names by which it may be
+// selected, the first of which names it in the changes.
+func (differ *differ) diff(path [][]string, scope fieldScope, oldValue reflect.Value, newValue reflect.Value) {
+	// Comparators of pointer types apply before the pointers are dereferenced.
+	if oldValue.IsValid() && newValue.IsValid() && oldValue.Type() == newValue.Type() && oldValue.CanInterface() &&
+		differ.compare(path, scope, oldValue, newValue) {
+		return
+	}
+
+	oldValue, newValue = indirect(oldValue), indirect(newValue)
+	// Fields promoted through unexported embedded structs can't be read.
+	if (oldValue.IsValid() && !oldValue.CanInterface()) || (newValue.IsValid() && !newValue.CanInterface()) {
+		return
+	}
+
+	switch {
+	case !oldValue.IsValid() && !newValue.IsValid():
+		return
+	case !oldValue.IsValid():
+		differ.add(path, scope, EntryAdded, nil, newValue.Interface())
+		return
+	case !newValue.IsValid():
+		differ.add(path, scope, EntryRemoved, oldValue.Interface(), nil)
+		return
+	case oldValue.Type() != newValue.Type():
+		differ.add(path, scope, EntryModified, oldValue.Interface(), newValue.Interface())
+		return
+	}
+
+	if differ.compare(path, scope, oldValue, newValue) {
+		return
+	}
+
+	switch oldValue.Kind() {
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(oldValue.Type()) {
+			// Embedded structs are traversed through their promoted fields, and unexported fields can't be read.
+			if !field.IsExported() || (field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct) ||
+				hasTagValue(field, dynconfIgnoreTagValue) {
+				continue
+			}
+
+			names := []string{field.Name}
+			if name := fieldTagName(field, differ.pathTags); name != "" && name != field.Name {
+				names = []string{name, field.Name}
+			}
+
+			fieldScope := fieldScope{
+				static: scope.static || hasTagValue(field, dynconfStaticTagValue),
+				secret: scope.secret || hasTagValue(field, dynconfSecretTagValue),
+			}
+			differ.diff(
+				append(slices.Clone(path), names),
+				fieldScope,
+				fieldByIndex(oldValue, field.Index),
+				fieldByIndex(newValue, field.Index),
+			)
+		}
+
+	case reflect.Map, reflect.Slice, reflect.Array:
+		oldKeys, oldEntries, oldErr := collectionEntries(oldValue)
+		newKeys, newEntries, newErr := collectionEntries(newValue)
+		// Collections whose entries can't be told apart by their keys, e.g. elements which share a key, change as one.
+		if oldErr != nil || newErr != nil {
+			if !differ.equality.equal(path, oldValue, newValue) {
+				differ.add(path, scope, EntryModified, oldValue.Interface(), newValue.Interface())
+			}
+			return
+		}
+
+		keys := oldKeys
+		for _, key := range newKeys {
+			if _, exists := oldEntries[key]; !exists {
+				keys = append(keys, key)
+			}
+		}
+		slices.SortStableFunc(keys, compareKeys)
+
+		for _, key := range keys {
+			differ.diff(append(slices.Clone(path), []string{key}), scope, oldEntries[key], newEntries[key])
+		}
+
+	default:
+		if !equalLeaves(oldValue, newValue) {
+			differ.add(path, scope, EntryModified, oldValue.Interface(), newValue.Interface())
+		}
+	}
+}
+
+// Compare the values by the comparator of their type, if there is one, and add a change if they aren't equal.
+// Returns whether there is such a comparator.
+func (differ *differ) compare(path [][]string, scope fieldScope, oldValue reflect.Value, newValue reflect.Value) bool {
+	compare, found := differ.equality.comparators[oldValue.Type()]
+	if !found {
+		return false
+	}
+
+	if !compare(oldValue.Interface(), newValue.Interface()) {
+		differ.add(path, scope, EntryModified, oldValue.Interface(), newValue.Interface())
+	}
+
+	return true
+}
+
+func (differ *differ) add(
+	path [][]string,
+	scope fieldScope,
+	kind EntryEvent,
+	oldConfiguration any,
+	newConfiguration any,
+) {
+	names := make([]string, 0, len(path))
+	for _, elementNames := range path {
+		names = append(names, elementNames[0])
+	}
+
+	change := Change{
+		Path:   pathToString(names),
+		Kind:   kind,
+		Old:    oldConfiguration,
+		New:    newConfiguration,
+		Secret: scope.secret,
+	}
+	differ.changes = append(differ.changes, change)
+
+	// A change of a part of the configuration which contains a static path, e.g. its removal, changes it too.
+	if scope.static || slices.ContainsFunc(differ.staticPaths, func(staticPath []string) bool {
+		return maskPathHasPrefix(path, staticPath) || maskPathIsPrefixOf(path, staticPath)
+	}) {
+		differ.staticChanges = append(differ.staticChanges, change)
+	}
+}
+
+// Dereference pointers and interfaces. Nil pointers and interfaces are returned as invalid values, like missing ones.
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+
+	return value
+}
+
+func indirectType(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	return valueType
+}
+
+// Get the field of the struct value, or an invalid value if it is promoted through a nil embedded pointer.
+func fieldByIndex(structValue reflect.Value, index []int) reflect.Value {
+	fieldValue, err := structValue.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+
+	return fieldValue
+}
@@ -0,0 +1,206 @@
+package manager_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func TestDiff(t *testing.T) {
+	oldConfiguration := testutils.MockConfigurationWithCollections{
+		Tenants: map[string]testutils.MockConfigurationA{"acme": {Value: "a"}, "initech": {Value: "b"}},
+		Shards:  []testutils.MockConfigurationB{{Value: false}},
+		Pipelines: []*testutils.MockConfigurationPipeline{
+			{Name: "logs", Value: "1"},
+			{Name: "traces", Value: "2"},
+		},
+	}
+	newConfiguration := testutils.MockConfigurationWithCollections{
+		Tenants: map[string]testutils.MockConfigurationA{"acme": {Value: "c"}, "globex": {Value: "d"}},
+		Shards:  []testutils.MockConfigurationB{{Value: false}, {Value: true}},
+		Pipelines: []*testutils.MockConfigurationPipeline{
+			{Name: "traces", Value: "2"},
+			{Name: "logs", Value: "3"},
+		},
+	}
+
+	expectedChanges := []manager.Change{
+		{Path: "Tenants.acme.Value", Kind: manager.EntryModified, Old: "a", New: "c"},
+		{Path: "Tenants.globex", Kind: manager.EntryAdded, New: testutils.MockConfigurationA{Value: "d"}},
+		{Path: "Tenants.initech", Kind: manager.EntryRemoved, Old: testutils.MockConfigurationA{Value: "b"}},
+		{Path: "Shards.1", Kind: manager.EntryAdded, New: testutils.MockConfigurationB{Value: true}},
+		{Path: "Pipelines.logs.Value", Kind: manager.EntryModified, Old: "1", New: "3"},
+	}
+
+	changes := manager.Diff(oldConfiguration, newConfiguration)
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("expected changes %v but got %v", expectedChanges, changes)
+	}
+
+	if changes[0].String() != "Tenants.acme.Value: a -> c" {
+		t.Fatalf("wrong string of modified change: %s", changes[0].String())
+	}
+	if changes[1].String() != "Tenants.globex: added {d}" {
+		t.Fatalf("wrong string of added change: %s", changes[1].String())
+	}
+	if changes[2].String() != "Tenants.initech: removed {b}" {
+		t.Fatalf("wrong string of removed change: %s", changes[2].String())
+	}
+
+	if changes := manager.Diff(newConfiguration, newConfiguration); len(changes) != 0 {
+		t.Fatalf("expected no changes between equal configurations, but got %v", changes)
+	}
+}
+
+func TestDiffOfCollectionsWithoutKeys(t *testing.T) {
+	oldConfiguration := testutils.MockConfigurationWithCollections{
+		Pipelines: []*testutils.MockConfigurationPipeline{
+			{Name: "logs", Value: "1"},
+			{Name: "logs", Value: "2"},
+		},
+	}
+	newConfiguration := testutils.MockConfigurationWithCollections{
+		Pipelines: []*testutils.MockConfigurationPipeline{
+			{Name: "logs", Value: "1"},
+			{Name: "logs", Value: "3"},
+		},
+	}
+
+	// Elements which share a key can't be told apart, so the collection changes as one.
+	expectedChanges := []manager.Change{
+		{
+			Path: "Pipelines",
+			Kind: manager.EntryModified,
+			Old:  oldConfiguration.Pipelines,
+			New:  newConfiguration.Pipelines,
+		},
+	}
+	if changes := manager.Diff(oldConfiguration, newConfiguration); !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("expected changes %v but got %v", expectedChanges, changes)
+	}
+	if changes := manager.Diff(oldConfiguration, oldConfiguration); len(changes) != 0 {
+		t.Fatalf("expected no changes between equal configurations, but got %v", changes)
+	}
+
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithCollections](
+		"testDiffWithoutKeys",
+		manager.Options{StaticPaths: [][]string{{"Pipelines"}}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+	if err := mgr.OnConfigurationUpdate(oldConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, manager.ErrStaticFieldChanged) {
+		t.Fatalf("wrong error when updating a static collection without keys: %#v", err)
+	}
+}
+
+func TestDiffOfSecrets(t *testing.T) {
+	oldConfiguration := testutils.RandomMockConfigurationWithDatabase()
+	newConfiguration := oldConfiguration
+	newConfiguration.Database.Password += "new"
+	newConfiguration.Replica = &testutils.MockConfigurationDatabase{Address: "replica", Password: "password"}
+
+	changes := manager.Diff(oldConfiguration, newConfiguration)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes but got %v", changes)
+	}
+
+	if !changes[0].Secret || changes[0].String() != "Database.Password: [REDACTED] -> [REDACTED]" {
+		t.Fatalf("expected the change of the password to be secret, but got %#v", changes[0])
+	}
+	if changes[1].Secret || changes[1].String() != "Replica: added {replica }" {
+		t.Fatalf("expected the password of the replica to be redacted, but got %s", changes[1])
+	}
+
+	redacted, _ := manager.Redact(newConfiguration).(testutils.MockConfigurationWithDatabase)
+	if redacted.Database.Password != "" || redacted.Replica.Password != "" ||
+		redacted.Database.Address != newConfiguration.Database.Address ||
+		newConfiguration.Replica.Password != "password" {
+		t.Fatalf("wrong redaction of %#v: %#v", newConfiguration, redacted)
+	}
+}
+
+func TestDiffWithPathTags(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithTags](
+		"testDiffWithPathTags",
+		manager.Options{PathTags: []string{"mapstructure", "json"}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	oldConfiguration := testutils.RandomMockConfigurationWithTags()
+	newConfiguration := oldConfiguration
+	newConfiguration.StorageConfig.Value += "bla"
+	newConfiguration.IngestConfig.Value = !newConfiguration.IngestConfig.Value
+
+	expectedChanges := []manager.Change{
+		{
+			Path: "storage_config.Value",
+			Kind: manager.EntryModified,
+			Old:  oldConfiguration.StorageConfig.Value,
+			New:  newConfiguration.StorageConfig.Value,
+		},
+		{
+			Path: "ingest_config.Value",
+			Kind: manager.EntryModified,
+			Old:  oldConfiguration.IngestConfig.Value,
+			New:  newConfiguration.IngestConfig.Value,
+		},
+	}
+
+	if changes := mgr.Diff(oldConfiguration, newConfiguration); !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("expected changes %v but got %v", expectedChanges, changes)
+	}
+}
+
+func TestLastChanges(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testLastChanges")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	// Callbacks may get the last changes during an update, which are those of the previous update.
+	initialB := mockConfiguration.B
+	var changesInCallback []manager.Change
+	if _, err := mgr.Register([]string{"B"}, func(cfg testutils.MockConfigurationB) error {
+		changesInCallback = mgr.LastChanges()
+		if cfg != initialB {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	oldValue := mockConfiguration.A.Value
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	expectedChanges := []manager.Change{
+		{Path: "A.Value", Kind: manager.EntryModified, Old: oldValue, New: mockConfiguration.A.Value},
+	}
+	if changes := mgr.LastChanges(); !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("after updating, expected last changes %v but got %v", expectedChanges, changes)
+	}
+
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	if changes := mgr.LastChanges(); !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("after rejected update, expected last changes %v but got %v", expectedChanges, changes)
+	}
+	if !reflect.DeepEqual(changesInCallback, expectedChanges) {
+		t.Fatalf("during update, expected last changes %v but got %v", expectedChanges, changesInCallback)
+	}
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"slices"
+	"sync"
+)
+
+// Call the given function for each of the configurables, which are given in the order in which they should be called.
+// Returns the indices of the configurables for which the function succeeded, in order, and the first error in order.
+//
+// By default the configurables are called one after the other, stopping at the first error. If parallel callbacks are
+// allowed, consecutive configurables which are independent of each other form a stage and are called concurrently. All
+// of the configurables of a stage are called even if some of them fail, but no further stages are called.
+func (mgr *DynamicConfigurationManager[Configuration]) dispatch(
+	configurables []*registeredConfigurable,
+	f func(i int) error,
+) ([]int, error) {
+	succeeded := make([]int, 0, len(configurables))
+
+	for _, stage := range mgr.splitToStages(configurables) {
+		errs := make([]error, len(stage))
+
+		if len(stage) == 1 {
+			errs[0] = f(stage[0])
+		} else {
+			workers := make(chan struct{}, mgr.options.MaxParallelCallbacks)
+			var wg sync.WaitGroup
+			for i, index := range stage {
+				wg.Add(1)
+				workers <- struct{}{}
+				go func() {
+					defer wg.Done()
+					defer func() { <-workers }()
+					errs[i] = f(index)
+				}()
+			}
+			wg.Wait()
+		}
+
+		var firstErr error
+		for i, index := range stage {
+			if errs[i] == nil {
+				succeeded = append(succeeded, index)
+			} else if firstErr == nil {
+				firstErr = errs[i]
+			}
+		}
+
+		if firstErr != nil {
+			return succeeded, firstErr
+		}
+	}
+
+	return succeeded, nil
+}
+
+// Split the configurables into stages of consecutive configurables which can be called concurrently. Each stage is
+// given as the indices of its configurables.
+// Configurables are independent if their paths are disjoint, they have the same priority, and neither depends on the
+// other.
+func (mgr *DynamicConfigurationManager[Configuration]) splitToStages(configurables []*registeredConfigurable) [][]int {
+	stages := make([][]int, 0, len(configurables))
+	if mgr.options.MaxParallelCallbacks <= 1 {
+		for i := range configurables {
+			stages = append(stages, []int{i})
+		}
+		return stages
+	}
+
+	for i, configurable := range configurables {
+		if len(stages) > 0 {
+			last := stages[len(stages)-1]
+			independent := !slices.ContainsFunc(last, func(j int) bool {
+				return !areIndependent(configurables[j], configurable)
+			})
+
+			if independent {
+				stages[len(stages)-1] = append(last, i)
+				continue
+			}
+		}
+
+		stages = append(stages, []int{i})
+	}
+
+	return stages
+}
+
+func areIndependent(a *registeredConfigurable, b *registeredConfigurable) bool {
+	if a.options.Priority != b.options.Priority {
+		return false
+	}
+
+	if isPathPrefix(a.path, b.path) || isPathPrefix(b.path, a.path) {
+		return false
+	}
+
+	dependsOn := func(configurable *registeredConfigurable, other *registeredConfigurable) bool {
+		return slices.ContainsFunc(configurable.options.DependsOn, func(dependency []string) bool {
+			return isPathPrefix(dependency, other.path)
+		})
+	}
+
+	return !dependsOn(a, b) && !dependsOn(b, a)
+}
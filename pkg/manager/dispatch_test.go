@@ -0,0 +1,125 @@
+package manager_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func newParallelConfigurationManager(id string, maxParallelCallbacks int) (
+	*manager.DynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels],
+	testutils.MockConfigurationWithTwoDepthLevels,
+	error,
+) {
+	mockConfiguration := testutils.RandomMockConfigurationWithTwoDepthLevels()
+
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithTwoDepthLevels](
+		id,
+		manager.Options{MaxParallelCallbacks: maxParallelCallbacks, CallbackTimeout: 5 * time.Second},
+	)
+	if err != nil {
+		return nil, mockConfiguration, err
+	}
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		return nil, mockConfiguration, err
+	}
+
+	return mgr, mockConfiguration, nil
+}
+
+func TestParallelCallbacks(t *testing.T) {
+	mgr, mockConfiguration, err := newParallelConfigurationManager("testParallelCallbacks", 3)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	// Each callback waits for all of the others to start, so the update only succeeds if they are called concurrently.
+	var started sync.WaitGroup
+	started.Add(3)
+	initial := true
+	waitForAll := func() error {
+		if initial {
+			return nil
+		}
+		started.Done()
+		started.Wait()
+		return nil
+	}
+
+	callbackA := func(testutils.MockConfigurationA) error { return waitForAll() }
+	callbackB := func(testutils.MockConfigurationB) error { return waitForAll() }
+	if _, err := mgr.Register([]string{"First", "A"}, callbackA); err != nil {
+		t.Fatalf("failed to register on First.A: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "A"}, callbackA); err != nil {
+		t.Fatalf("failed to register on Second.A: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"First", "B"}, callbackB); err != nil {
+		t.Fatalf("failed to register on First.B: %#v", err)
+	}
+	initial = false
+
+	mockConfiguration.First.A.Value += "bla"
+	mockConfiguration.Second.A.Value += "bla"
+	mockConfiguration.First.B.Value = !mockConfiguration.First.B.Value
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+}
+
+func TestParallelCallbacksRestoration(t *testing.T) {
+	mgr, mockConfiguration, err := newParallelConfigurationManager("testParallelCallbacksRestoration", 4)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	origConfiguration := mockConfiguration
+
+	var lock sync.Mutex
+	copyConfiguration := testutils.MockConfigurationWithTwoDepthLevels{}
+	if _, err := mgr.Register([]string{"First", "A"}, func(cfg testutils.MockConfigurationA) error {
+		lock.Lock()
+		defer lock.Unlock()
+		copyConfiguration.First.A = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on First.A: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "A"}, func(cfg testutils.MockConfigurationA) error {
+		lock.Lock()
+		defer lock.Unlock()
+		copyConfiguration.Second.A = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on Second.A: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"First", "B"}, func(cfg testutils.MockConfigurationB) error {
+		if cfg != origConfiguration.First.B {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on First.B: %#v", err)
+	}
+
+	mockConfiguration.First.A.Value += "bla"
+	mockConfiguration.Second.A.Value += "bla"
+	mockConfiguration.First.B.Value = !mockConfiguration.First.B.Value
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	if !reflect.DeepEqual(copyConfiguration.First.A, origConfiguration.First.A) ||
+		!reflect.DeepEqual(copyConfiguration.Second.A, origConfiguration.Second.A) {
+		t.Fatalf(
+			"after failing to update to illegal configuration, expected restoration to original %#v but got %#v",
+			origConfiguration,
+			copyConfiguration,
+		)
+	}
+}
@@ -0,0 +1,316 @@
+package manager
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// A path element which selects all of the entries of a map, or all of the elements of a slice or an array.
+// A path may have at most one wildcard.
+const wildcardPathElement = "*"
+
+// The change of a single entry selected by a wildcard path.
+type EntryEvent int
+
+const (
+	// The entry exists in the new configuration but not in the old one.
+	EntryAdded EntryEvent = iota
+	// The entry exists in both configurations, and has changed.
+	EntryModified
+	// The entry exists in the old configuration but not in the new one.
+	EntryRemoved
+)
+
+func (event EntryEvent) String() string {
+	switch event {
+	case EntryAdded:
+		return "added"
+	case EntryModified:
+		return "modified"
+	case EntryRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+func splitWildcardPath(path []string) ([]string, []string, bool) {
+	index := slices.Index(path, wildcardPathElement)
+	if index == -1 {
+		return nil, nil, false
+	}
+
+	return path[:index], path[index+1:], true
+}
+
+// Get the configurations of all of the entries selected by a wildcard path which have changed. Entries are ordered by
+// their keys. Entries which can't be found are only counted by the error metric if this isn't a preview.
+func (mgr *DynamicConfigurationManager[Configuration]) getEntriesConfigurations(
+	oldConfiguration Configuration,
+	newConfiguration Configuration,
+	path []string,
+	preview bool,
+) ([]pathConfigurations, error) {
+	prefix, suffix, _ := splitWildcardPath(path)
+
+	oldEntries, err := mgr.getEntries(oldConfiguration, prefix, suffix)
+	if err != nil {
+		if !preview {
+			mgr.metrics.oldPathConfigurationDoesNotExist.Inc()
+		}
+		return nil, fmt.Errorf("failed to find old configuration of path %s: %w", pathToString(path), err)
+	}
+
+	newEntries, err := mgr.getEntries(newConfiguration, prefix, suffix)
+	if err != nil {
+		if !preview {
+			mgr.metrics.newPathConfigurationDoesNotExist.Inc()
+		}
+		return nil, fmt.Errorf("failed to find new configuration of path %s: %w", pathToString(path), err)
+	}
+
+	keys := make([]string, 0, len(oldEntries.keys)+len(newEntries.keys))
+	keys = append(keys, oldEntries.keys...)
+	for _, key := range newEntries.keys {
+		if _, exists := oldEntries.values[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortStableFunc(keys, compareKeys)
+
+	entriesConfigurations := make([]pathConfigurations, 0)
+	for _, key := range keys {
+		oldValue, oldExists := oldEntries.values[key]
+		newValue, newExists := newEntries.values[key]
+
+		configurations := pathConfigurations{key: key, old: oldValue, new: newValue}
+		switch {
+		case !oldExists:
+			configurations.event = EntryAdded
+		case !newExists:
+			configurations.event = EntryRemoved
+		case !mgr.equal(oldValue, newValue):
+			configurations.event = EntryModified
+		default:
+			continue
+		}
+
+		configurations.changed = true
+		entriesConfigurations = append(entriesConfigurations, configurations)
+	}
+
+	return entriesConfigurations, nil
+}
+
+// Get the configurations of all of the entries selected by a wildcard path, as if they were all added.
+func (mgr *DynamicConfigurationManager[Configuration]) getAddedEntriesConfigurations(
+	cfg Configuration,
+	path []string,
+) ([]pathConfigurations, error) {
+	prefix, suffix, _ := splitWildcardPath(path)
+
+	entries, err := mgr.getEntries(cfg, prefix, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(entries.keys, compareKeys)
+
+	entriesConfigurations := make([]pathConfigurations, 0, len(entries.keys))
+	for _, key := range entries.keys {
+		entriesConfigurations = append(entriesConfigurations, pathConfigurations{
+			key:     key,
+			event:   EntryAdded,
+			new:     entries.values[key],
+			changed: true,
+		})
+	}
+
+	return entriesConfigurations, nil
+}
+
+// The entries selected by a wildcard path within a single configuration.
+type entries struct {
+	keys   []string
+	values map[string]any
+}
+
+// Get all of the entries of the map, slice or array under the prefix, each traversed by the suffix.
+func (mgr *DynamicConfigurationManager[Configuration]) getEntries(
+	cfg Configuration,
+	prefix []string,
+	suffix []string,
+) (entries, error) {
+	collection, err := mgr.getValueByPath(cfg, prefix)
+	if err != nil {
+		return entries{}, err
+	}
+
+	keys, values, err := collectionEntries(reflect.ValueOf(collection))
+	if err != nil {
+		return entries{}, fmt.Errorf("can't select all entries of path %s: %w", pathToString(prefix), err)
+	}
+
+	result := entries{keys: make([]string, 0, len(keys)), values: make(map[string]any, len(keys))}
+	for _, key := range keys {
+		entry := values[key]
+		if entry.Kind() == reflect.Ptr {
+			if entry.IsNil() {
+				return entries{}, fmt.Errorf(
+					"entry %s of path %s is nil: %w",
+					key,
+					pathToString(prefix),
+					ErrNoMatchingFieldFound,
+				)
+			}
+			entry = entry.Elem()
+		}
+
+		// An entry under which the suffix selects an entry that doesn't exist is skipped, as if it doesn't exist.
+		value, err := mgr.getValueByPath(entry.Interface(), suffix)
+		if errors.Is(err, ErrNoMatchingEntryFound) {
+			continue
+		}
+		if err != nil {
+			return entries{}, fmt.Errorf("failed to traverse entry %s of path %s: %w", key, pathToString(prefix), err)
+		}
+
+		result.keys = append(result.keys, key)
+		result.values[key] = value
+	}
+
+	return result, nil
+}
+
+// Get the entries of the map, slice or array, by the keys which select them as path elements (see entryByKey).
+// Map entries are in no particular order, and slice and array elements are in their order.
+// Each entry must be selected by its key alone, so if several entries have the same key, if a map key can't be given
+// as a path element, or if an element with a key field is nil, ErrInvalidEntryKey is returned.
+func collectionEntries(collection reflect.Value) ([]string, map[string]reflect.Value, error) {
+	switch collection.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, collection.Len())
+		values := make(map[string]reflect.Value, collection.Len())
+		for _, key := range collection.MapKeys() {
+			keyString := fmt.Sprint(key.Interface())
+			if parsed, err := parseMapKey(key.Type(), keyString); err != nil || !parsed.Equal(key) {
+				return nil, nil, fmt.Errorf(
+					"%w: map key %s can't be given as a path element",
+					ErrInvalidEntryKey,
+					keyString,
+				)
+			}
+
+			keys = append(keys, keyString)
+			values[keyString] = collection.MapIndex(key)
+		}
+		return keys, values, nil
+
+	case reflect.Slice, reflect.Array:
+		keyField, hasKeyField := keyFieldOf(collection.Type().Elem())
+
+		keys := make([]string, 0, collection.Len())
+		values := make(map[string]reflect.Value, collection.Len())
+		for i := 0; i < collection.Len(); i++ {
+			key := strconv.Itoa(i)
+			if hasKeyField {
+				elemVal := collection.Index(i)
+				if elemVal.Kind() == reflect.Ptr {
+					if elemVal.IsNil() {
+						return nil, nil, fmt.Errorf("%w: element %d is nil, so it has no key", ErrInvalidEntryKey, i)
+					}
+					elemVal = elemVal.Elem()
+				}
+				key = fmt.Sprint(elemVal.FieldByIndex(keyField.Index).Interface())
+			}
+
+			if _, exists := values[key]; exists {
+				return nil, nil, fmt.Errorf("%w: several elements have the key %s", ErrInvalidEntryKey, key)
+			}
+			keys = append(keys, key)
+			values[key] = collection.Index(i)
+		}
+		return keys, values, nil
+
+	default:
+		return nil, nil, fmt.Errorf(
+			"%w: %s is not a map, a slice or an array",
+			ErrNoMatchingFieldFound,
+			collection.Kind(),
+		)
+	}
+}
+
+// Order keys numerically if both are numbers (e.g. slice indices), and lexicographically otherwise.
+func compareKeys(a string, b string) int {
+	aNumber, aErr := strconv.Atoi(a)
+	bNumber, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		return cmp.Compare(aNumber, bNumber)
+	}
+
+	return cmp.Compare(a, b)
+}
+
+// Get the type of the entries selected by a wildcard path. The type is resolved statically, as there may be no
+// entries at all. Like configurations under other paths, pointers to entries are dereferenced.
+func (mgr *DynamicConfigurationManager[Configuration]) getEntryType(cfg any, path []string) (reflect.Type, error) {
+	prefix, suffix, _ := splitWildcardPath(path)
+
+	collection, err := mgr.getValueByPath(cfg, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entryType := reflect.TypeOf(collection)
+	if entryType.Kind() != reflect.Map && entryType.Kind() != reflect.Slice && entryType.Kind() != reflect.Array {
+		return nil, fmt.Errorf(
+			"%w: can't select all entries of %s in path %s",
+			ErrNoMatchingFieldFound,
+			entryType.Kind(),
+			pathToString(path),
+		)
+	}
+	entryType = entryType.Elem()
+
+	for _, field := range suffix {
+		if entryType.Kind() == reflect.Ptr {
+			entryType = entryType.Elem()
+		}
+
+		switch entryType.Kind() {
+		case reflect.Struct:
+			structField, found := mgr.structFieldByName(entryType, field)
+			if !found {
+				return nil, fmt.Errorf(
+					"field %s does not exist in struct type %s with path %s: %w",
+					field,
+					entryType,
+					pathToString(path),
+					ErrNoMatchingFieldFound,
+				)
+			}
+			entryType = structField.Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			entryType = entryType.Elem()
+		default:
+			return nil, fmt.Errorf(
+				"can't access field %s of non-struct type %s in path %s: %w",
+				field,
+				entryType.Kind(),
+				pathToString(path),
+				ErrNoMatchingFieldFound,
+			)
+		}
+	}
+
+	if entryType.Kind() == reflect.Ptr {
+		entryType = entryType.Elem()
+	}
+
+	return entryType, nil
+}
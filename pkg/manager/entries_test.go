@@ -0,0 +1,267 @@
+package manager_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func newInitiatedConfigurationManagerWithCollections(id string) (
+	*manager.DynamicConfigurationManager[testutils.MockConfigurationWithCollections],
+	testutils.MockConfigurationWithCollections,
+	error,
+) {
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections](id)
+	if err != nil {
+		return nil, mockConfiguration, err
+	}
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		return nil, mockConfiguration, err
+	}
+
+	return mgr, mockConfiguration, nil
+}
+
+func TestWildcardSubscription(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithCollections("testWildcardSubscription")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	entryCalls := make([]string, 0)
+	if _, err := manager.SubscribeEntries(
+		mgr,
+		[]string{"Tenants", "*"},
+		func(key string, cfg testutils.MockConfigurationA) error {
+			entryCalls = append(entryCalls, key+"="+cfg.Value)
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register on wildcard path: %#v", err)
+	}
+
+	eventCalls := make([]string, 0)
+	if _, err := manager.SubscribeEntryEvents(
+		mgr,
+		[]string{"Pipelines", "*", "Value"},
+		func(key string, event manager.EntryEvent, value string) error {
+			eventCalls = append(eventCalls, fmt.Sprintf("%s:%s=%s", key, event, value))
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register on wildcard path with suffix: %#v", err)
+	}
+
+	expectedEntryCalls := []string{
+		"acme=" + mockConfiguration.Tenants["acme"].Value,
+		"initech=" + mockConfiguration.Tenants["initech"].Value,
+	}
+	if !slices.Equal(entryCalls, expectedEntryCalls) {
+		t.Fatalf("after registering, got calls %v (expected %v)", entryCalls, expectedEntryCalls)
+	}
+
+	expectedEventCalls := []string{
+		"logs:added=" + mockConfiguration.Pipelines[0].Value,
+		"traces:added=" + mockConfiguration.Pipelines[1].Value,
+	}
+	if !slices.Equal(eventCalls, expectedEventCalls) {
+		t.Fatalf("after registering, got calls %v (expected %v)", eventCalls, expectedEventCalls)
+	}
+
+	entryCalls, eventCalls = entryCalls[:0], eventCalls[:0]
+	oldLogs := mockConfiguration.Pipelines[0].Value
+	mockConfiguration = testutils.MockConfigurationWithCollections{
+		Tenants: map[string]testutils.MockConfigurationA{
+			"acme":   mockConfiguration.Tenants["acme"],
+			"globex": {Value: "globex"},
+		},
+		Pipelines: []*testutils.MockConfigurationPipeline{
+			{Name: "traces", Value: mockConfiguration.Pipelines[1].Value + "bla"},
+			{Name: "metrics", Value: "metrics"},
+		},
+	}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	expectedEntryCalls = []string{"globex=globex"}
+	if !slices.Equal(entryCalls, expectedEntryCalls) {
+		t.Fatalf("after updating, got calls %v (expected %v)", entryCalls, expectedEntryCalls)
+	}
+
+	expectedEventCalls = []string{
+		"logs:removed=" + oldLogs,
+		"metrics:added=metrics",
+		"traces:modified=" + mockConfiguration.Pipelines[0].Value,
+	}
+	if !slices.Equal(eventCalls, expectedEventCalls) {
+		t.Fatalf("after updating, got calls %v (expected %v)", eventCalls, expectedEventCalls)
+	}
+}
+
+func TestWildcardSubscriptionRestoration(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithCollections("testWildcardRestoration")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	shards := make(map[string]testutils.MockConfigurationB)
+	if _, err := mgr.Register(
+		[]string{"Shards", "*"},
+		func(key string, event manager.EntryEvent, cfg testutils.MockConfigurationB) error {
+			if event == manager.EntryRemoved {
+				delete(shards, key)
+			} else {
+				shards[key] = cfg
+			}
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register on wildcard path: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Tenants"}, func(cfg map[string]testutils.MockConfigurationA) error {
+		if len(cfg) == 0 {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on map: %#v", err)
+	}
+
+	origShards := map[string]testutils.MockConfigurationB{
+		"0": mockConfiguration.Shards[0],
+		"1": mockConfiguration.Shards[1],
+	}
+	mockConfiguration = testutils.MockConfigurationWithCollections{
+		Shards: []testutils.MockConfigurationB{{Value: !mockConfiguration.Shards[0].Value}},
+	}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	if fmt.Sprint(shards) != fmt.Sprint(origShards) {
+		t.Fatalf("after failing to update to illegal configuration, expected %v but got %v", origShards, shards)
+	}
+}
+
+func TestAddedEntryRestoration(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithCollections("testAddedEntryRestoration")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var copyAcme testutils.MockConfigurationA
+	if _, err := mgr.Register(
+		[]string{"Tenants", "acme"},
+		func(oldCfg testutils.MockConfigurationA, newCfg testutils.MockConfigurationA) error {
+			copyAcme = newCfg
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register on map entry: %#v", err)
+	}
+
+	tenants := make(map[string]testutils.MockConfigurationA)
+	if _, err := mgr.Register([]string{"Tenants", "*"}, func(key string, cfg testutils.MockConfigurationA) error {
+		tenants[key] = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on wildcard path: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Shards"}, func(cfg []testutils.MockConfigurationB) error {
+		if len(cfg) == 0 {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on slice: %#v", err)
+	}
+
+	// Removing the entry isn't delivered to either callback, so adding it back is.
+	acme := mockConfiguration.Tenants["acme"]
+	delete(mockConfiguration.Tenants, "acme")
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration after removing map entry: %#v", err)
+	}
+	delete(tenants, "acme")
+
+	acme.Value += "bla"
+	mockConfiguration = testutils.MockConfigurationWithCollections{
+		Tenants: map[string]testutils.MockConfigurationA{
+			"acme":    acme,
+			"initech": mockConfiguration.Tenants["initech"],
+			"globex":  {Value: "globex"},
+		},
+	}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	// The added entries are restored to the zero value, which stands for their absence.
+	if copyAcme != (testutils.MockConfigurationA{}) {
+		t.Fatalf("after failing to update to illegal configuration, expected entry restored but got %#v", copyAcme)
+	}
+	if tenants["acme"] != (testutils.MockConfigurationA{}) || tenants["globex"] != (testutils.MockConfigurationA{}) {
+		t.Fatalf("after failing to update to illegal configuration, expected entries restored but got %v", tenants)
+	}
+}
+
+func TestWildcardPathWithDuplicateKeys(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithCollections("testWildcardDuplicateKeys")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if _, err := mgr.Register(
+		[]string{"Pipelines", "*"},
+		func(key string, cfg testutils.MockConfigurationPipeline) error {
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register on wildcard path: %#v", err)
+	}
+
+	duplicate := &testutils.MockConfigurationPipeline{Name: "logs"}
+	mockConfiguration.Pipelines = append(mockConfiguration.Pipelines, duplicate)
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, manager.ErrInvalidEntryKey) {
+		t.Fatalf("wrong error when updating to configuration with duplicate keys: %#v", err)
+	}
+
+	mockConfiguration.Pipelines = []*testutils.MockConfigurationPipeline{nil}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, manager.ErrInvalidEntryKey) {
+		t.Fatalf("wrong error when updating to configuration with nil element: %#v", err)
+	}
+}
+
+func TestRegisterOnWildcardPathWithBadCallback(t *testing.T) {
+	mgr, _, err := newInitiatedConfigurationManagerWithCollections("testWildcardBadCallback")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Tenants", "*"}, func(cfg testutils.MockConfigurationA) error {
+		return nil
+	}); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering callback without key on wildcard path: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Tenants", "*", "*"}, func(key string, cfg testutils.MockConfigurationA) error {
+		return nil
+	}); !errors.Is(err, manager.ErrInvalidPath) {
+		t.Fatalf("wrong error when registering on path with two wildcards: %#v", err)
+	}
+
+	var out testutils.MockConfigurationA
+	if err := mgr.Get([]string{"Tenants", "*"}, &out); !errors.Is(err, manager.ErrInvalidPath) {
+		t.Fatalf("wrong error when getting wildcard path: %#v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// A custom equality of values of a single type, by which changes of such values are detected instead of comparing
+// them field by field. Create comparators with CompareAs. Values whose comparison panics are deemed unequal.
+type Comparator struct {
+	Type  reflect.Type
+	Equal func(oldValue any, newValue any) bool
+}
+
+// Create a comparator of values of type T, e.g. to compare structs by a version field, or floats with a tolerance.
+func CompareAs[T any](equal func(oldValue T, newValue T) bool) Comparator {
+	return Comparator{
+		Type: reflect.TypeFor[T](),
+		Equal: func(oldValue any, newValue any) bool {
+			oldTyped, _ := oldValue.(T)
+			newTyped, _ := newValue.(T)
+			return equal(oldTyped, newTyped)
+		},
+	}
+}
+
+// Whether the configuration update changes the configurations of the path as far as the registration is concerned.
+// The configurations are already known to have changed as far as the manager is concerned, so this only narrows the
+// changes down by the registration's field masks and equality function.
+func (mgr *DynamicConfigurationManager[Configuration]) changedFor(
+	configurable *registeredConfigurable,
+	configurations pathConfigurations,
+) bool {
+	if configurations.event != EntryModified {
+		return true
+	}
+
+	options := configurable.options
+	if len(options.Fields) > 0 || len(options.IgnoreFields) > 0 {
+		mask := fieldMask{include: options.Fields, exclude: options.IgnoreFields}
+		oldValue, newValue := reflect.ValueOf(configurations.old), reflect.ValueOf(configurations.new)
+		if mgr.newEquality(mask).equal(nil, oldValue, newValue) {
+			return false
+		}
+	}
+
+	return options.Equal == nil || !mgr.metrics.callEqual(options.Equal, configurations.old, configurations.new)
+}
+
+// Call a custom equality, deeming the values unequal if it panics, so that modules are still given the new
+// configuration rather than failing the whole configuration update.
+func (metrics *DynamicConfigurationManagerMetrics) callEqual(
+	equal func(oldValue any, newValue any) bool,
+	oldValue any,
+	newValue any,
+) (isEqual bool) {
+	err := metrics.recovered(func() { isEqual = equal(oldValue, newValue) })
+	return err == nil && isEqual
+}
+
+// Whether two configurations are equal as far as change detection is concerned, see equality.
+func (mgr *DynamicConfigurationManager[Configuration]) equal(oldConfiguration any, newConfiguration any) bool {
+	return mgr.newEquality(fieldMask{}).equal(nil, reflect.ValueOf(oldConfiguration), reflect.ValueOf(newConfiguration))
+}
+
+func (mgr *DynamicConfigurationManager[Configuration]) newEquality(mask fieldMask) *equality {
+	return newEquality(mgr.options.PathTags, mgr.options.Comparators, mask, mgr.metrics)
+}
+
+// Compares configurations for change detection. Unlike reflect.DeepEqual:
+//   - Fields tagged `dynconf:"ignore"` are skipped, as are fields outside of the field mask.
+//   - Values of types with a comparator are compared by it.
+//   - NaN floats are equal to each other, and funcs are equal if they are the same function.
+//   - Nil and empty maps and slices are equal, as both have no entries.
+type equality struct {
+	pathTags    []string
+	comparators map[reflect.Type]func(oldValue any, newValue any) bool
+	mask        fieldMask
+	// Pairs of pointers, maps and slices being compared, which are considered equal if they are reached again while
+	// comparing them, to handle cycles.
+	visited map[visit]bool
+}
+
+type visit struct {
+	oldPointer uintptr
+	newPointer uintptr
+	valueType  reflect.Type
+}
+
+// Create an equality with the given comparators, whose panics are counted by the metrics.
+func newEquality(
+	pathTags []string,
+	comparators []Comparator,
+	mask fieldMask,
+	metrics *DynamicConfigurationManagerMetrics,
+) *equality {
+	equality := &equality{
+		pathTags:    pathTags,
+		comparators: make(map[reflect.Type]func(oldValue any, newValue any) bool, len(comparators)),
+		mask:        mask,
+		visited:     make(map[visit]bool),
+	}
+	for _, comparator := range comparators {
+		equality.comparators[comparator.Type] = func(oldValue any, newValue any) bool {
+			return metrics.callEqual(comparator.Equal, oldValue, newValue)
+		}
+	}
+
+	return equality
+}
+
+// Compare the values under the path. Each path element holds the names by which it may be selected, e.g. the Go name
+// of a field and its tag name.
+func (equality *equality) equal(path [][]string, oldValue reflect.Value, newValue reflect.Value) bool {
+	within, related := equality.mask.covers(path)
+	if !related {
+		return true
+	}
+
+	switch {
+	case !oldValue.IsValid() || !newValue.IsValid():
+		return oldValue.IsValid() == newValue.IsValid()
+	case oldValue.Type() != newValue.Type():
+		return false
+	}
+
+	// Fields outside of the mask may only be compared on the way to the fields within it.
+	if compare, found := equality.comparators[oldValue.Type()]; found && within && oldValue.CanInterface() {
+		return compare(oldValue.Interface(), newValue.Interface())
+	}
+
+	switch oldValue.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if oldValue.IsNil() || newValue.IsNil() {
+			if oldValue.Kind() == reflect.Ptr {
+				return oldValue.IsNil() == newValue.IsNil()
+			}
+		} else if oldValue.Pointer() == newValue.Pointer() &&
+			(oldValue.Kind() == reflect.Ptr || oldValue.Len() == newValue.Len()) {
+			return true
+		}
+
+		visit := visit{oldPointer: oldValue.Pointer(), newPointer: newValue.Pointer(), valueType: oldValue.Type()}
+		if equality.visited[visit] {
+			return true
+		}
+		equality.visited[visit] = true
+		defer delete(equality.visited, visit)
+	}
+
+	switch oldValue.Kind() {
+	case reflect.Ptr:
+		return equality.equal(path, oldValue.Elem(), newValue.Elem())
+
+	case reflect.Interface:
+		if oldValue.IsNil() || newValue.IsNil() {
+			return oldValue.IsNil() == newValue.IsNil()
+		}
+		return equality.equal(path, oldValue.Elem(), newValue.Elem())
+
+	case reflect.Struct:
+		for i := 0; i < oldValue.NumField(); i++ {
+			field := oldValue.Type().Field(i)
+			if hasTagValue(field, dynconfIgnoreTagValue) {
+				continue
+			}
+
+			// Fields of embedded structs are selected as promoted fields.
+			fieldPath := path
+			if !field.Anonymous {
+				fieldPath = append(slices.Clone(path), equality.fieldNames(field))
+			}
+
+			if !equality.equal(fieldPath, oldValue.Field(i), newValue.Field(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Map:
+		for _, key := range oldValue.MapKeys() {
+			keyPath := append(slices.Clone(path), []string{fmt.Sprint(key)})
+			if !equality.equal(keyPath, oldValue.MapIndex(key), newValue.MapIndex(key)) {
+				return false
+			}
+		}
+		for _, key := range newValue.MapKeys() {
+			if oldValue.MapIndex(key).IsValid() {
+				continue
+			}
+
+			keyPath := append(slices.Clone(path), []string{fmt.Sprint(key)})
+			if !equality.equal(keyPath, reflect.Value{}, newValue.MapIndex(key)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < max(oldValue.Len(), newValue.Len()); i++ {
+			oldElement, newElement := elementAt(oldValue, i), elementAt(newValue, i)
+			elementPath := append(slices.Clone(path), elementNames(oldValue.Type().Elem(), i, oldElement, newElement))
+			if !equality.equal(elementPath, oldElement, newElement) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return equalLeaves(oldValue, newValue)
+	}
+}
+
+// Get the names by which the field may be selected in a path: its Go name, and its name by the path tags.
+func (equality *equality) fieldNames(field reflect.StructField) []string {
+	names := []string{field.Name}
+	if name := fieldTagName(field, equality.pathTags); name != "" {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func elementAt(value reflect.Value, index int) reflect.Value {
+	if index >= value.Len() {
+		return reflect.Value{}
+	}
+
+	return value.Index(index)
+}
+
+// Get the names by which the element of a slice or array may be selected in a path: its index, or the values of its
+// key field if its type has one (see entryByKey).
+func elementNames(elemType reflect.Type, index int, elements ...reflect.Value) []string {
+	keyField, found := keyFieldOf(elemType)
+	if !found {
+		return []string{strconv.Itoa(index)}
+	}
+
+	names := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if element = indirect(element); element.IsValid() {
+			names = append(names, fmt.Sprint(element.FieldByIndex(keyField.Index)))
+		}
+	}
+
+	return names
+}
+
+func equalLeaves(oldValue reflect.Value, newValue reflect.Value) bool {
+	switch oldValue.Kind() {
+	case reflect.Bool:
+		return oldValue.Bool() == newValue.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return oldValue.Int() == newValue.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return oldValue.Uint() == newValue.Uint()
+	case reflect.Float32, reflect.Float64:
+		return equalFloats(oldValue.Float(), newValue.Float())
+	case reflect.Complex64, reflect.Complex128:
+		oldComplex, newComplex := oldValue.Complex(), newValue.Complex()
+		return equalFloats(real(oldComplex), real(newComplex)) && equalFloats(imag(oldComplex), imag(newComplex))
+	case reflect.String:
+		return oldValue.String() == newValue.String()
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return oldValue.Pointer() == newValue.Pointer()
+	default:
+		return false
+	}
+}
+
+func equalFloats(oldFloat float64, newFloat float64) bool {
+	return oldFloat == newFloat || (math.IsNaN(oldFloat) && math.IsNaN(newFloat))
+}
+
+// Relative paths which select the fields that are compared. If any paths are included, only fields within them are
+// compared, and fields within the excluded paths are never compared. Path elements match the names of fields, map
+// keys and slice elements, as in paths given to Register.
+type fieldMask struct {
+	include [][]string
+	exclude [][]string
+}
+
+// Whether the path is within the fields compared by the mask, and whether it is related to them at all, i.e. whether
+// it is within them or leads to them.
+func (mask fieldMask) covers(path [][]string) (within bool, related bool) {
+	for _, excluded := range mask.exclude {
+		if maskPathHasPrefix(path, excluded) {
+			return false, false
+		}
+	}
+
+	if len(mask.include) == 0 {
+		return true, true
+	}
+
+	for _, included := range mask.include {
+		if maskPathHasPrefix(path, included) {
+			return true, true
+		}
+		if maskPathIsPrefixOf(path, included) {
+			related = true
+		}
+	}
+
+	return false, related
+}
+
+func maskPathHasPrefix(path [][]string, prefix []string) bool {
+	return len(path) >= len(prefix) && maskPathIsPrefixOf(path[:len(prefix)], prefix)
+}
+
+func maskPathIsPrefixOf(path [][]string, other []string) bool {
+	if len(path) > len(other) {
+		return false
+	}
+
+	for i, names := range path {
+		if !slices.Contains(names, other[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,239 @@
+package manager_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func hook() {}
+
+func initialServiceConfiguration() testutils.MockConfigurationWithService {
+	return testutils.MockConfigurationWithService{
+		Service: testutils.MockConfigurationService{
+			Limits: testutils.MockConfigurationLimits{Memory: 512, CPU: 2},
+			Ratio:  math.NaN(),
+			Hook:   hook,
+		},
+	}
+}
+
+// Register on the service, and count the calls which aren't the initial call.
+func registerCountingCalls(
+	t *testing.T,
+	mgr *manager.DynamicConfigurationManager[testutils.MockConfigurationWithService],
+	options manager.RegisterOptions,
+) *int {
+	calls := -1
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"Service"},
+		func(cfg testutils.MockConfigurationService) error {
+			calls++
+			return nil
+		},
+		options,
+	); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	return &calls
+}
+
+func TestIgnoredFieldsAreNotChanges(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithService](
+		"testIgnoredFieldsAreNotChanges",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := initialServiceConfiguration()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	calls := registerCountingCalls(t, mgr, manager.RegisterOptions{})
+
+	// The revision is ignored, the ratio is still NaN and the hook is the same function.
+	mockConfiguration.Service.Revision++
+	mockConfiguration.Service.Ratio = math.NaN()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *calls != 0 {
+		t.Fatalf("callback was called %d times upon an update of ignored fields", *calls)
+	}
+	if changes := mgr.LastChanges(); len(changes) != 0 {
+		t.Fatalf("expected no changes upon an update of ignored fields but got %v", changes)
+	}
+
+	mockConfiguration.Service.Limits.CPU++
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *calls != 1 {
+		t.Fatalf("expected callback to be called once upon a change but it was called %d times", *calls)
+	}
+}
+
+func TestRegisterWithFieldMasks(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithService](
+		"testRegisterWithFieldMasks",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := initialServiceConfiguration()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	memoryCalls := registerCountingCalls(t, mgr, manager.RegisterOptions{Fields: [][]string{{"Limits", "Memory"}}})
+	nonLimitsCalls := registerCountingCalls(t, mgr, manager.RegisterOptions{IgnoreFields: [][]string{{"Limits"}}})
+
+	mockConfiguration.Service.Limits.CPU++
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *memoryCalls != 0 || *nonLimitsCalls != 0 {
+		t.Fatalf("callbacks were called upon a change outside of their masks: %d, %d", *memoryCalls, *nonLimitsCalls)
+	}
+
+	mockConfiguration.Service.Limits.Memory++
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *memoryCalls != 1 || *nonLimitsCalls != 0 {
+		t.Fatalf("wrong calls upon a change of the memory limit: %d, %d", *memoryCalls, *nonLimitsCalls)
+	}
+
+	mockConfiguration.Service.Ratio = 0.5
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *memoryCalls != 1 || *nonLimitsCalls != 1 {
+		t.Fatalf("wrong calls upon a change of the ratio: %d, %d", *memoryCalls, *nonLimitsCalls)
+	}
+}
+
+func TestRegisterWithUnknownFieldMasks(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithService](
+		"testRegisterWithUnknownFieldMasks",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	if err := mgr.OnConfigurationUpdate(initialServiceConfiguration()); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	for _, options := range []manager.RegisterOptions{
+		{Fields: [][]string{{"Limits", "Disk"}}},
+		{IgnoreFields: [][]string{{"Ratio", "Value"}}},
+	} {
+		if _, err := mgr.RegisterWithOptions(
+			[]string{"Service"},
+			func(cfg testutils.MockConfigurationService) error { return nil },
+			options,
+		); !errors.Is(err, manager.ErrNoMatchingFieldFound) {
+			t.Fatalf("wrong error when registering with field masks %v: %#v", options, err)
+		}
+	}
+}
+
+func TestPanickingEqualities(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithService](
+		"testPanickingEqualities",
+		manager.Options{Comparators: []manager.Comparator{
+			manager.CompareAs(func(oldLimits, newLimits testutils.MockConfigurationLimits) bool {
+				panic("can't compare limits")
+			}),
+		}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := initialServiceConfiguration()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	calls := registerCountingCalls(t, mgr, manager.RegisterOptions{
+		Equal: func(oldConfiguration, newConfiguration any) bool {
+			panic("can't compare services")
+		},
+	})
+
+	mockConfiguration.Service.Limits.CPU++
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *calls != 1 {
+		t.Fatalf("expected callback to be called once upon a change whose comparison panics, but got %d", *calls)
+	}
+}
+
+func TestComparators(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithService](
+		"testComparators",
+		manager.Options{Comparators: []manager.Comparator{
+			manager.CompareAs(func(oldLimits, newLimits testutils.MockConfigurationLimits) bool {
+				return oldLimits.Memory == newLimits.Memory
+			}),
+		}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := initialServiceConfiguration()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	calls := registerCountingCalls(t, mgr, manager.RegisterOptions{})
+	ratioCalls := registerCountingCalls(t, mgr, manager.RegisterOptions{
+		Equal: func(oldConfiguration, newConfiguration any) bool {
+			oldService := oldConfiguration.(testutils.MockConfigurationService)
+			newService := newConfiguration.(testutils.MockConfigurationService)
+			return math.IsNaN(oldService.Ratio) == math.IsNaN(newService.Ratio)
+		},
+	})
+
+	mockConfiguration.Service.Limits.CPU++
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *calls != 0 {
+		t.Fatalf("callback was called %d times upon a change deemed equal by the comparator", *calls)
+	}
+
+	mockConfiguration.Service.Limits.Memory++
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *calls != 1 || *ratioCalls != 0 {
+		t.Fatalf("wrong calls upon a change of the memory limit: %d, %d", *calls, *ratioCalls)
+	}
+
+	expectedChanges := []manager.Change{{
+		Path: "Service.Limits",
+		Kind: manager.EntryModified,
+		Old:  testutils.MockConfigurationLimits{Memory: 512, CPU: 3},
+		New:  testutils.MockConfigurationLimits{Memory: 513, CPU: 3},
+	}}
+	if changes := mgr.LastChanges(); !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("expected changes %v but got %v", expectedChanges, changes)
+	}
+
+	mockConfiguration.Service.Ratio = 0.5
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+	if *calls != 2 || *ratioCalls != 1 {
+		t.Fatalf("wrong calls upon a change of the ratio: %d, %d", *calls, *ratioCalls)
+	}
+}
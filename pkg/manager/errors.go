@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The error returned when a registered module rejects a configuration update. It tells which module rejected the
+// update and why, and whether restoring the modules which already accepted it failed, in which case the modules may
+// be left with inconsistent configurations.
+// The error wraps the rejection error, so errors.Is and errors.As see through it.
+type UpdateError struct {
+	// The path of the rejecting registration.
+	Path string
+	// For wildcard paths, the key of the rejected entry.
+	Key string
+	// The name of the rejecting registration, as given in its options.
+	Name string
+	// The error with which the module rejected the configuration.
+	Err error
+	// The errors with which modules failed to restore their previous configuration, in the order of restoration.
+	RestoreFailures []error
+}
+
+func (updateError *UpdateError) Error() string {
+	module := "registered module"
+	if updateError.Name != "" {
+		module = fmt.Sprintf("registered module %s", updateError.Name)
+	}
+
+	path := updateError.Path
+	if updateError.Key != "" {
+		path = fmt.Sprintf("%s (entry %s)", path, updateError.Key)
+	}
+
+	message := fmt.Sprintf("%s doesn't allow new configuration for path %s: %v", module, path, updateError.Err)
+	if restoreError := updateError.RestoreError(); restoreError != nil {
+		message = fmt.Sprintf("%s; failed to restore previous configuration: %v", message, restoreError)
+	}
+
+	return message
+}
+
+func (updateError *UpdateError) Unwrap() error {
+	return updateError.Err
+}
+
+// Get the restore failures joined into a single error, or nil if restoration succeeded.
+func (updateError *UpdateError) RestoreError() error {
+	return errors.Join(updateError.RestoreFailures...)
+}
+
+func newUpdateError(configurable *registeredConfigurable, configurations pathConfigurations, err error) *UpdateError {
+	return &UpdateError{
+		Path: configurable.pathString,
+		Key:  configurations.key,
+		Name: configurable.options.Name,
+		Err:  err,
+	}
+}
+
+// The error with which a registered module is deemed to reject a configuration when its callback panics. The panic is
+// recovered, so the configuration update fails like any other rejection, and restoration occurs.
+// The error matches ErrCallbackPanicked, and if the panic value is an error, it wraps that error too.
+type PanicError struct {
+	// The value with which the callback panicked.
+	Value any
+	// The stack trace of the panicking goroutine, as of the panic. It isn't part of the error's message, which is a
+	// single line.
+	Stack []byte
+}
+
+func (panicError *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrCallbackPanicked, panicError.Value)
+}
+
+func (panicError *PanicError) Unwrap() []error {
+	if err, isError := panicError.Value.(error); isError {
+		return []error{ErrCallbackPanicked, err}
+	}
+
+	return []error{ErrCallbackPanicked}
+}
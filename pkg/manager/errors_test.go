@@ -0,0 +1,95 @@
+package manager_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+var errCantRestore = errors.New("can't restore")
+
+func TestUpdateError(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testUpdateError")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	initialA := mockConfiguration.A
+	calls := 0
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		calls++
+		if calls > 2 && cfg == initialA {
+			return errCantRestore
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register first callback: %#v", err)
+	}
+
+	initialB := mockConfiguration.B
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"B"},
+		func(cfg testutils.MockConfigurationB) error {
+			if cfg != initialB {
+				return errors.ErrUnsupported
+			}
+			return nil
+		},
+		manager.RegisterOptions{Name: "moduleB"},
+	); err != nil {
+		t.Fatalf("failed to register second callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	err = mgr.OnConfigurationUpdate(mockConfiguration)
+
+	var updateError *manager.UpdateError
+	if !errors.As(err, &updateError) {
+		t.Fatalf("expected update error, but got %#v", err)
+	}
+	if updateError.Path != "B" || updateError.Name != "moduleB" || !errors.Is(updateError.Err, errors.ErrUnsupported) {
+		t.Fatalf("wrong rejecting module in update error: %#v", updateError)
+	}
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("update error doesn't wrap the rejection error: %#v", err)
+	}
+
+	if len(updateError.RestoreFailures) != 1 || !errors.Is(updateError.RestoreError(), errCantRestore) {
+		t.Fatalf("expected a single restore failure, but got %#v", updateError.RestoreFailures)
+	}
+	if !strings.Contains(err.Error(), "moduleB") || !strings.Contains(err.Error(), errCantRestore.Error()) {
+		t.Fatalf("update error message doesn't describe the rejection and the restore failure: %s", err)
+	}
+}
+
+func TestUpdateErrorWithoutRestoreFailures(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testUpdateErrorNoRestore")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	initialA := mockConfiguration.A
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		if cfg != initialA {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	err = mgr.OnConfigurationUpdate(mockConfiguration)
+
+	var updateError *manager.UpdateError
+	if !errors.As(err, &updateError) {
+		t.Fatalf("expected update error, but got %#v", err)
+	}
+	if updateError.Path != "A" || updateError.RestoreError() != nil {
+		t.Fatalf("wrong update error: %#v", updateError)
+	}
+}
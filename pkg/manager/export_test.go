@@ -0,0 +1,6 @@
+package manager
+
+// Get the number of configuration updates requested so far, so that tests can wait for updates to be requested.
+func (mgr *DynamicConfigurationManager[Configuration]) RequestedUpdates() uint64 {
+	return mgr.requestedUpdates.Load()
+}
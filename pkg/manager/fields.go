@@ -0,0 +1,307 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	dynconfTag            = "dynconf"
+	dynconfKeyTagValue    = "key"
+	dynconfIgnoreTagValue = "ignore"
+	dynconfStaticTagValue = "static"
+	dynconfSecretTagValue = "secret"
+)
+
+// Find the field of the struct value which is named by the given path element.
+// By default, path elements are Go field names. If path tags are configured, a field may also be named by the first of
+// the configured tags which names it, falling back to the Go field name.
+func (mgr *DynamicConfigurationManager[Configuration]) fieldByName(
+	structValue reflect.Value,
+	name string,
+) (reflect.Value, bool) {
+	field, found := mgr.structFieldByName(structValue.Type(), name)
+	if !found {
+		return reflect.Value{}, false
+	}
+
+	fieldValue, err := structValue.FieldByIndexErr(field.Index)
+	if err != nil { // the field is promoted through a nil embedded pointer
+		return reflect.Value{}, false
+	}
+
+	return fieldValue, true
+}
+
+func (mgr *DynamicConfigurationManager[Configuration]) structFieldByName(
+	structType reflect.Type,
+	name string,
+) (reflect.StructField, bool) {
+	if len(mgr.options.PathTags) > 0 {
+		var match *reflect.StructField
+		for _, field := range reflect.VisibleFields(structType) {
+			if !field.IsExported() || fieldTagName(field, mgr.options.PathTags) != name {
+				continue
+			}
+
+			// Prefer the shallowest field, as Go does for promoted fields.
+			if match == nil || len(field.Index) < len(match.Index) {
+				match = &field
+			}
+		}
+
+		if match != nil {
+			return *match, true
+		}
+	}
+
+	// Unexported fields can't be read through reflection, so they can't be part of a path.
+	field, found := structType.FieldByName(name)
+	if !found || !field.IsExported() {
+		return reflect.StructField{}, false
+	}
+
+	return field, true
+}
+
+// Get the path in which each field is named by a single name, however it was named in the given path, so that paths
+// which select the same fields are equal. Fields are named by their names by the path tags, falling back to their Go
+// names, as in the changes of configuration updates. Path elements which don't name fields, such as map keys and
+// wildcards, are kept as is, as are the path elements after a field which can't be found.
+func (mgr *DynamicConfigurationManager[Configuration]) canonicalPath(path []string) []string {
+	canonical := slices.Clone(path)
+
+	elemType := reflect.TypeFor[Configuration]()
+	for i, element := range path {
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+
+		switch elemType.Kind() {
+		case reflect.Struct:
+			field, found := mgr.structFieldByName(elemType, element)
+			if !found {
+				return canonical
+			}
+
+			canonical[i] = field.Name
+			if name := fieldTagName(field, mgr.options.PathTags); name != "" {
+				canonical[i] = name
+			}
+			elemType = field.Type
+
+		case reflect.Map, reflect.Slice, reflect.Array:
+			elemType = elemType.Elem()
+
+		default:
+			return canonical
+		}
+	}
+
+	return canonical
+}
+
+// Get the name given to the field by the first of the path tags which names it, or an empty string if there is no
+// such tag.
+func fieldTagName(field reflect.StructField, pathTags []string) string {
+	for _, tag := range pathTags {
+		if name := tagName(field, tag); name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Get the name given to the field by the tag, ignoring any tag options such as "omitempty". Returns an empty string
+// if there is no such name, or if the field is ignored by the tag ("-").
+func tagName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// Check that the path within values of the given type selects something, e.g. a field mask of a registration (see
+// RegisterOptions.Fields). The path is resolved statically, as the entries it selects may not exist yet: map keys must
+// only be valid keys of the map, and elements of slices and arrays must only be selected by a valid index, or by any
+// value of their key field. Paths within interfaces can't be resolved, so they are always valid.
+func (mgr *DynamicConfigurationManager[Configuration]) resolveTypePath(valueType reflect.Type, path []string) error {
+	for _, element := range path {
+		for valueType.Kind() == reflect.Ptr {
+			valueType = valueType.Elem()
+		}
+
+		switch valueType.Kind() {
+		case reflect.Interface:
+			return nil
+
+		case reflect.Struct:
+			field, found := mgr.structFieldByName(valueType, element)
+			if !found {
+				return fmt.Errorf(
+					"%w: field %s does not exist in struct type %s with path %s",
+					ErrNoMatchingFieldFound,
+					element,
+					valueType,
+					pathToString(path),
+				)
+			}
+			valueType = field.Type
+
+		case reflect.Map:
+			if _, err := parseMapKey(valueType.Key(), element); err != nil {
+				return fmt.Errorf("%w: in path %s", err, pathToString(path))
+			}
+			valueType = valueType.Elem()
+
+		case reflect.Slice, reflect.Array:
+			if _, found := keyFieldOf(valueType.Elem()); !found {
+				index, err := strconv.Atoi(element)
+				if err != nil || index < 0 || (valueType.Kind() == reflect.Array && index >= valueType.Len()) {
+					return fmt.Errorf(
+						"%w: index %s isn't valid for %s in path %s",
+						ErrNoMatchingEntryFound,
+						element,
+						valueType,
+						pathToString(path),
+					)
+				}
+			}
+			valueType = valueType.Elem()
+
+		default:
+			return fmt.Errorf(
+				"%w: can't access field %s of non-struct type %s in path %s",
+				ErrNoMatchingFieldFound,
+				element,
+				valueType.Kind(),
+				pathToString(path),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Find the entry of the map, slice or array value which is selected by the given path element.
+// Map entries are selected by their keys. Slice and array elements are selected by their indices, unless they are
+// structs with a key field, in which case they are selected by the value of that field.
+func entryByKey(value reflect.Value, key string) (reflect.Value, error) {
+	if value.Kind() == reflect.Map {
+		keyVal, err := parseMapKey(value.Type().Key(), key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		entryVal := value.MapIndex(keyVal)
+		if !entryVal.IsValid() {
+			return reflect.Value{}, ErrNoMatchingEntryFound
+		}
+
+		return entryVal, nil
+	}
+
+	if keyField, found := keyFieldOf(value.Type().Elem()); found {
+		for i := 0; i < value.Len(); i++ {
+			elemVal := value.Index(i)
+			if elemVal.Kind() == reflect.Ptr {
+				if elemVal.IsNil() {
+					continue
+				}
+				elemVal = elemVal.Elem()
+			}
+
+			if fmt.Sprint(elemVal.FieldByIndex(keyField.Index).Interface()) == key {
+				return value.Index(i), nil
+			}
+		}
+
+		return reflect.Value{}, ErrNoMatchingEntryFound
+	}
+
+	index, err := strconv.Atoi(key)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("%w: index %s is not a number", ErrNoMatchingEntryFound, key)
+	}
+
+	if index < 0 || index >= value.Len() {
+		return reflect.Value{}, ErrNoMatchingEntryFound
+	}
+
+	return value.Index(index), nil
+}
+
+// Find the key field of the struct type (or pointer to struct type), which is tagged `dynconf:"key"`.
+func keyFieldOf(elemType reflect.Type) (reflect.StructField, bool) {
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+
+	for _, field := range reflect.VisibleFields(elemType) {
+		if field.IsExported() && hasTagValue(field, dynconfKeyTagValue) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// Whether the field's dynconf tag has the given value, e.g. `dynconf:"key"` or `dynconf:"key,ignore"`.
+func hasTagValue(field reflect.StructField, value string) bool {
+	return slices.Contains(strings.Split(field.Tag.Get(dynconfTag), ","), value)
+}
+
+// Convert the path element to a map key of the given type. String, integer, float and boolean keys are supported.
+func parseMapKey(keyType reflect.Type, key string) (reflect.Value, error) {
+	keyVal := reflect.New(keyType).Elem()
+
+	var err error
+	switch keyType.Kind() {
+	case reflect.String:
+		keyVal.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var parsed int64
+		if parsed, err = strconv.ParseInt(key, 10, keyType.Bits()); err == nil {
+			keyVal.SetInt(parsed)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var parsed uint64
+		if parsed, err = strconv.ParseUint(key, 10, keyType.Bits()); err == nil {
+			keyVal.SetUint(parsed)
+		}
+	case reflect.Float32, reflect.Float64:
+		var parsed float64
+		if parsed, err = strconv.ParseFloat(key, keyType.Bits()); err == nil {
+			keyVal.SetFloat(parsed)
+		}
+	case reflect.Bool:
+		var parsed bool
+		if parsed, err = strconv.ParseBool(key); err == nil {
+			keyVal.SetBool(parsed)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("%w: unsupported map key type %s", ErrNoMatchingEntryFound, keyType)
+	}
+
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf(
+			"%w: key %s is not a valid %s: %w",
+			ErrNoMatchingEntryFound,
+			key,
+			keyType,
+			err,
+		)
+	}
+
+	return keyVal, nil
+}
@@ -0,0 +1,336 @@
+package manager_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func TestPathsResolvedByTags(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithTags](
+		"testPathsResolvedByTags",
+		manager.Options{PathTags: []string{"mapstructure", "json"}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithTags()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has tags: %v", err)
+	}
+
+	paths := []struct {
+		path     []string
+		expected any
+	}{
+		{path: []string{"storage_config"}, expected: mockConfiguration.StorageConfig},
+		{path: []string{"StorageConfig"}, expected: mockConfiguration.StorageConfig},
+		{path: []string{"ingest_config"}, expected: mockConfiguration.IngestConfig},
+		{path: []string{"Ignored"}, expected: mockConfiguration.Ignored},
+	}
+	for _, path := range paths {
+		out := reflect.New(reflect.TypeOf(path.expected))
+		if err := mgr.Get(path.path, out.Interface()); err != nil {
+			t.Fatalf("failed to get path %v: %v", path.path, err)
+		}
+
+		if !reflect.DeepEqual(out.Elem().Interface(), path.expected) {
+			t.Fatalf(
+				"after getting path %v, expected %#v but got %#v",
+				path.path,
+				path.expected,
+				out.Elem().Interface(),
+			)
+		}
+	}
+
+	var out testutils.MockConfigurationA
+	if err := mgr.Get([]string{"storageConfig"}, &out); !errors.Is(err, manager.ErrNoMatchingFieldFound) {
+		t.Fatalf("wrong error when getting path of a tag of lower priority: %#v", err)
+	}
+
+	var copyConfiguration testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"storage_config"}, func(cfg testutils.MockConfigurationA) error {
+		copyConfiguration = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on tagged path: %v", err)
+	}
+
+	mockConfiguration.StorageConfig.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	if !reflect.DeepEqual(copyConfiguration, mockConfiguration.StorageConfig) {
+		t.Fatalf(
+			"after updating configuration, expected %#v but got %#v",
+			mockConfiguration.StorageConfig,
+			copyConfiguration,
+		)
+	}
+}
+
+func TestPathsCanonicalizedByTags(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithTags](
+		"testPathsCanonicalizedByTags",
+		manager.Options{PathTags: []string{"mapstructure", "json"}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithTags()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has tags: %v", err)
+	}
+
+	// The dependency names the field by its tag, and the registration it depends on by its Go name.
+	calls := make([]string, 0)
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"IngestConfig"},
+		func(testutils.MockConfigurationB) error {
+			calls = append(calls, "ingest")
+			return nil
+		},
+		manager.RegisterOptions{DependsOn: [][]string{{"storage_config"}}},
+	); err != nil {
+		t.Fatalf("failed to register ingest: %v", err)
+	}
+
+	errRejected := errors.New("rejected")
+	origValue := mockConfiguration.StorageConfig.Value
+	if _, err := mgr.Register([]string{"StorageConfig", "Value"}, func(value string) error {
+		calls = append(calls, "storage")
+		if value != origValue {
+			return errRejected
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register storage: %v", err)
+	}
+
+	mockConfiguration.StorageConfig.Value += "bla"
+	mockConfiguration.IngestConfig.Value = !mockConfiguration.IngestConfig.Value
+	calls = calls[:0]
+	err = mgr.OnConfigurationUpdate(mockConfiguration)
+	var updateError *manager.UpdateError
+	if !errors.As(err, &updateError) || !errors.Is(err, errRejected) {
+		t.Fatalf("wrong error when storage rejects configuration: %#v", err)
+	}
+
+	if !slices.Equal(calls, []string{"storage"}) {
+		t.Fatalf("callbacks called in order %v, although ingest depends on storage", calls)
+	}
+	if updateError.Path != "storage_config.Value" {
+		t.Fatalf("rejected path is %s (expected the canonical path %s)", updateError.Path, "storage_config.Value")
+	}
+}
+
+func TestRegisterOnMapKeyWithPathSeparator(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections](
+		"testMapKeyWithPathSeparator",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+	mockConfiguration.Tenants["acme.com"] = testutils.MockConfigurationA{Value: "acme"}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has collections: %v", err)
+	}
+
+	var copyConfiguration testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"Tenants", "acme.com"}, func(cfg testutils.MockConfigurationA) error {
+		copyConfiguration = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on map key with path separator: %v", err)
+	}
+
+	mockConfiguration.Tenants = map[string]testutils.MockConfigurationA{"acme.com": {Value: "acme2"}}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	if !reflect.DeepEqual(copyConfiguration, mockConfiguration.Tenants["acme.com"]) {
+		t.Fatalf(
+			"after updating configuration, expected %#v but got %#v",
+			mockConfiguration.Tenants["acme.com"],
+			copyConfiguration,
+		)
+	}
+}
+
+func TestRegisterOnPathsWithTheSameString(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections]("testSameString")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+	mockConfiguration.Tenants = map[string]testutils.MockConfigurationA{
+		"acme":       {Value: "acme"},
+		"acme.Value": {Value: "dotted"},
+	}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has collections: %v", err)
+	}
+
+	// Both paths are displayed as "Tenants.acme.Value", but they are different paths.
+	var dotted []testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"Tenants", "acme.Value"}, func(cfg testutils.MockConfigurationA) error {
+		dotted = append(dotted, cfg)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on map key with path separator: %v", err)
+	}
+	var values []string
+	if _, err := mgr.Register([]string{"Tenants", "acme", "Value"}, func(cfg string) error {
+		values = append(values, cfg)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on field of map entry: %v", err)
+	}
+
+	mockConfiguration.Tenants = map[string]testutils.MockConfigurationA{
+		"acme":       {Value: "acme2"},
+		"acme.Value": {Value: "dotted"},
+	}
+	preview, err := mgr.PreviewConfigurationUpdate(mockConfiguration)
+	if err != nil {
+		t.Fatalf("failed to preview configuration update: %v", err)
+	}
+	if len(preview.Paths) != 2 || preview.Paths[0].Changed == preview.Paths[1].Changed {
+		t.Fatalf("expected only one of the paths to be previewed as changed, but got %#v", preview.Paths)
+	}
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	expectedDotted := []testutils.MockConfigurationA{{Value: "dotted"}}
+	if !reflect.DeepEqual(dotted, expectedDotted) {
+		t.Fatalf("expected the unchanged entry to be delivered once, as %#v, but got %#v", expectedDotted, dotted)
+	}
+	if expectedValues := []string{"acme", "acme2"}; !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("expected the field of the changed entry to be delivered as %#v but got %#v", expectedValues, values)
+	}
+}
+
+func TestPathsNotResolvedByTagsByDefault(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithTags]("testPathsNotByTags")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	var out testutils.MockConfigurationA
+	if err := mgr.Get([]string{"storage_config"}, &out); !errors.Is(err, manager.ErrNoMatchingFieldFound) {
+		t.Fatalf("wrong error when getting tagged path without path tags: %#v", err)
+	}
+}
+
+func TestRegisterOnCollectionEntries(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections](
+		"testRegisterOnCollectionEntries",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration that has collections: %v", err)
+	}
+
+	timesAcme := 0
+	var copyAcme testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"Tenants", "acme"}, func(cfg testutils.MockConfigurationA) error {
+		timesAcme++
+		copyAcme = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on map entry: %v", err)
+	}
+
+	var copyShard testutils.MockConfigurationB
+	if _, err := mgr.Register([]string{"Shards", "1"}, func(cfg testutils.MockConfigurationB) error {
+		copyShard = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on slice element: %v", err)
+	}
+
+	var copyTraces testutils.MockConfigurationPipeline
+	if _, err := mgr.Register([]string{"Pipelines", "traces"}, func(cfg testutils.MockConfigurationPipeline) error {
+		copyTraces = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on slice element by key field: %v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Tenants", "globex"}, func(cfg testutils.MockConfigurationA) error {
+		return nil
+	}); !errors.Is(err, manager.ErrNoMatchingEntryFound) {
+		t.Fatalf("wrong error when registering on map entry that doesn't exist: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Shards", "2"}, func(cfg testutils.MockConfigurationB) error {
+		return nil
+	}); !errors.Is(err, manager.ErrNoMatchingEntryFound) {
+		t.Fatalf("wrong error when registering on slice element that doesn't exist: %#v", err)
+	}
+
+	mockConfiguration = testutils.MockConfigurationWithCollections{
+		Tenants: map[string]testutils.MockConfigurationA{
+			"acme":    mockConfiguration.Tenants["acme"],
+			"initech": {Value: mockConfiguration.Tenants["initech"].Value + "bla"},
+		},
+		Shards: []testutils.MockConfigurationB{
+			mockConfiguration.Shards[0],
+			{Value: !mockConfiguration.Shards[1].Value},
+		},
+		Pipelines: []*testutils.MockConfigurationPipeline{
+			{Name: "traces", Value: mockConfiguration.Pipelines[1].Value + "bla"},
+			mockConfiguration.Pipelines[0],
+		},
+	}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %v", err)
+	}
+
+	if timesAcme != 1 {
+		t.Fatalf("callback of map entry called although the entry didn't change")
+	}
+	if !reflect.DeepEqual(copyShard, mockConfiguration.Shards[1]) {
+		t.Fatalf("after updating configuration, expected %#v but got %#v", mockConfiguration.Shards[1], copyShard)
+	}
+	if !reflect.DeepEqual(copyTraces, *mockConfiguration.Pipelines[0]) {
+		t.Fatalf("after updating configuration, expected %#v but got %#v", *mockConfiguration.Pipelines[0], copyTraces)
+	}
+
+	// Removing the entry doesn't fail the update, and adding it back notifies the registration.
+	acme := mockConfiguration.Tenants["acme"]
+	mockConfiguration.Tenants = map[string]testutils.MockConfigurationA{"initech": mockConfiguration.Tenants["initech"]}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration after removing map entry: %v", err)
+	}
+	if timesAcme != 1 {
+		t.Fatalf("callback of map entry called after the entry was removed")
+	}
+
+	acme.Value += "bla"
+	mockConfiguration.Tenants = map[string]testutils.MockConfigurationA{"acme": acme}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration after adding map entry back: %v", err)
+	}
+	if timesAcme != 2 || !reflect.DeepEqual(copyAcme, acme) {
+		t.Fatalf("after adding map entry back, expected %#v but got %#v", acme, copyAcme)
+	}
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// An applied configuration, as kept in the configuration history.
+type HistoryEntry[Configuration any] struct {
+	// The version of the configuration. Versions start from 1 for the first applied configuration update, and are
+	// incremented upon every applied configuration update.
+	Version uint64
+	// The time at which the configuration was applied.
+	Time time.Time
+	// The source which the configuration update was labeled with (see WithSource).
+	Source string
+	// The applied configuration.
+	Configuration Configuration
+}
+
+// Get the version of the current configuration. Before the first configuration update, the version is 0.
+// This doesn't wait for configuration updates in progress, so it may be called from callbacks and observers.
+func (mgr *DynamicConfigurationManager[Configuration]) Version() uint64 {
+	return mgr.version.Load()
+}
+
+// Get the most recent applied configurations, oldest first. The last entry is the current configuration.
+// The number of entries is limited by the history size option.
+// This doesn't wait for configuration updates in progress, so it may be called from callbacks and observers.
+func (mgr *DynamicConfigurationManager[Configuration]) History() []HistoryEntry[Configuration] {
+	return slices.Clone(*mgr.history.Load())
+}
+
+// Apply the configuration of a past version again. This is a regular configuration update, so the registered modules
+// may reject it, in which case restoration occurs, and if it is applied it becomes a new version.
+// If the version isn't kept in the history, ErrVersionNotFound is returned.
+func (mgr *DynamicConfigurationManager[Configuration]) Rollback(version uint64) error {
+	return mgr.RollbackContext(context.Background(), version)
+}
+
+// Same as Rollback, where the given context is passed to the callbacks.
+func (mgr *DynamicConfigurationManager[Configuration]) RollbackContext(ctx context.Context, version uint64) error {
+	entry, found := mgr.historyEntry(version)
+	if !found {
+		return fmt.Errorf("%w: can't roll back to version %d", ErrVersionNotFound, version)
+	}
+
+	source := fmt.Sprintf("rollback to version %d", version)
+	if requestedBy := SourceFromContext(ctx); requestedBy != "" {
+		source = fmt.Sprintf("%s (%s)", requestedBy, source)
+	}
+
+	return mgr.OnConfigurationUpdateContext(WithSource(ctx, source), entry.Configuration)
+}
+
+func (mgr *DynamicConfigurationManager[Configuration]) historyEntry(
+	version uint64,
+) (HistoryEntry[Configuration], bool) {
+	for _, entry := range *mgr.history.Load() {
+		if entry.Version == version {
+			return entry, true
+		}
+	}
+
+	return HistoryEntry[Configuration]{}, false
+}
+
+// Record the current configuration in the history, dropping the oldest entries beyond the history size. The history
+// is published as a new slice, so that the slices gotten by readers are never modified.
+func (mgr *DynamicConfigurationManager[Configuration]) recordHistory(ctx context.Context) {
+	if mgr.options.HistorySize <= 0 {
+		return
+	}
+
+	history := *mgr.history.Load()
+	if excess := len(history) + 1 - mgr.options.HistorySize; excess > 0 {
+		history = history[excess:]
+	}
+
+	history = append(slices.Clip(history), HistoryEntry[Configuration]{
+		Version:       mgr.version.Load(),
+		Time:          time.Now(),
+		Source:        SourceFromContext(ctx),
+		Configuration: mgr.cfg,
+	})
+	mgr.history.Store(&history)
+}
@@ -0,0 +1,171 @@
+package manager_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func TestHistory(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testHistory",
+		manager.Options{HistorySize: 2},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	if version := mgr.Version(); version != 0 {
+		t.Fatalf("expected version 0 before the first update, but got %d", version)
+	}
+
+	configurations := make([]testutils.MockConfigurationWithOneDepthLevel, 0)
+	sources := []string{"first", "second", "third"}
+	for _, source := range sources {
+		mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+		configurations = append(configurations, mockConfiguration)
+
+		ctx := manager.WithSource(context.Background(), source)
+		if err := mgr.OnConfigurationUpdateContext(ctx, mockConfiguration); err != nil {
+			t.Fatalf("failed to update configuration: %#v", err)
+		}
+	}
+
+	if version := mgr.Version(); version != 3 {
+		t.Fatalf("expected version 3 after three updates, but got %d", version)
+	}
+
+	history := mgr.History()
+	if len(history) != 2 {
+		t.Fatalf("expected history of the two most recent versions, but got %d entries", len(history))
+	}
+	for i, entry := range history {
+		if entry.Version != uint64(i+2) || entry.Source != sources[i+1] || entry.Configuration != configurations[i+1] {
+			t.Fatalf("wrong history entry %d: %#v", i, entry)
+		}
+		if entry.Time.IsZero() {
+			t.Fatalf("history entry %d has no time", i)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testRollback",
+		manager.Options{HistorySize: 5},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	firstConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(firstConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var copyConfiguration testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		copyConfiguration = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	secondConfiguration := firstConfiguration
+	secondConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(secondConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	ctx := manager.WithSource(context.Background(), "admin")
+	if err := mgr.RollbackContext(ctx, 1); err != nil {
+		t.Fatalf("failed to roll back: %#v", err)
+	}
+
+	if copyConfiguration != firstConfiguration.A {
+		t.Fatalf("after rolling back, expected %#v but got %#v", firstConfiguration.A, copyConfiguration)
+	}
+
+	if version := mgr.Version(); version != 3 {
+		t.Fatalf("expected rollback to be applied as version 3, but got %d", version)
+	}
+
+	history := mgr.History()
+	if source := history[len(history)-1].Source; source != "admin (rollback to version 1)" {
+		t.Fatalf("wrong source of rollback: %s", source)
+	}
+
+	if err := mgr.Rollback(10); !errors.Is(err, manager.ErrVersionNotFound) {
+		t.Fatalf("wrong error when rolling back to unknown version: %#v", err)
+	}
+}
+
+func TestRejectedUpdateIsNotVersioned(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testRejectedUpdateIsNotVersioned")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		if cfg != mockConfiguration.A {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	newConfiguration := mockConfiguration
+	newConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	if version := mgr.Version(); version != 1 {
+		t.Fatalf("expected rejected update not to change version 1, but got %d", version)
+	}
+}
+
+func TestVersionAndHistoryFromCallback(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testVersionAndHistoryFromCallback",
+		manager.Options{HistorySize: 2},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	// During an update, callbacks see the version and history of the current configuration.
+	var versionInCallback uint64
+	var historyInCallback []manager.HistoryEntry[testutils.MockConfigurationWithOneDepthLevel]
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		versionInCallback = mgr.Version()
+		historyInCallback = mgr.History()
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if versionInCallback != 1 {
+		t.Fatalf("during update, expected version %d but got %d", 1, versionInCallback)
+	}
+	if len(historyInCallback) != 1 || historyInCallback[0].Version != 1 {
+		t.Fatalf("during update, expected history of version %d but got %#v", 1, historyInCallback)
+	}
+	if history := mgr.History(); len(history) != 2 || history[1].Version != 2 {
+		t.Fatalf("after update, expected history up to version %d but got %#v", 2, history)
+	}
+}
@@ -0,0 +1,301 @@
+package manager
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// How the configurations given to registered modules, and gotten through Get, are isolated from the manager's own
+// configuration, so that a module which modifies its configuration can't corrupt it for everyone else.
+type Isolation int
+
+const (
+	// Give each module, and each caller of Get and Snapshot, a deep copy of the configuration, and copy each new
+	// configuration before updating to it, so that the caller may keep modifying it. Funcs and channels can't be
+	// copied, so they are shared, as are unexported fields, which can't be set.
+	CopyConfigurations Isolation = iota
+	// Give modules the manager's own configuration, and check that they don't modify it by fingerprinting it before
+	// and after each call. A module which modifies its configuration is deemed to reject it with
+	// ErrConfigurationMutated. Meant for debugging, as fingerprinting is slower than copying.
+	DetectMutations
+	// Give modules the manager's own configuration without any checks. The configuration must be treated as
+	// immutable, which saves copying it.
+	ShareConfigurations
+)
+
+// Run the given function, which is given the configuration values, with the configurable's timeout applied. When
+// detecting mutations, the function is deemed to reject the configuration if it modifies any of the values.
+func (configurable *registeredConfigurable) deliver(
+	ctx context.Context,
+	values []reflect.Value,
+	f func(ctx context.Context) error,
+) error {
+	if configurable.isolation != DetectMutations {
+		return configurable.invoke(ctx, f)
+	}
+
+	fingerprints := make([]uint64, 0, len(values))
+	for _, value := range values {
+		fingerprints = append(fingerprints, fingerprint(value))
+	}
+
+	err := configurable.invoke(ctx, f)
+	// A function which timed out may still be running, so its values can't be checked.
+	if errors.Is(err, ErrCallbackTimeout) {
+		return err
+	}
+
+	for i, value := range values {
+		if fingerprint(value) != fingerprints[i] {
+			return errors.Join(err, fmt.Errorf(
+				"%w: by the module registered on path %s",
+				ErrConfigurationMutated,
+				configurable.pathString,
+			))
+		}
+	}
+
+	return err
+}
+
+// Isolate the configuration value before handing it out, according to the isolation option.
+func isolate(isolation Isolation, value reflect.Value) reflect.Value {
+	if isolation != CopyConfigurations {
+		return value
+	}
+
+	return deepCopy(value)
+}
+
+// Copy the value deeply, so that no map, slice or pointer is shared between the value and its copy. Pointers which
+// are shared within the value are shared within the copy as well.
+func deepCopy(value reflect.Value) reflect.Value {
+	copier := copier{copies: make(map[typedPointer]reflect.Value)}
+	return copier.copy(value)
+}
+
+// The value shown instead of the values of secret changes, e.g. when formatting them (see Change.Secret).
+const RedactedValue = "[REDACTED]"
+
+// Get a deep copy of the value in which fields tagged `dynconf:"secret"` are zeroed, e.g. to log or audit it. The value
+// itself isn't modified.
+func Redact(value any) any {
+	if value == nil {
+		return nil
+	}
+
+	copier := copier{copies: make(map[typedPointer]reflect.Value), redactSecrets: true}
+	return copier.copy(reflect.ValueOf(value)).Interface()
+}
+
+type typedPointer struct {
+	pointer   uintptr
+	valueType reflect.Type
+}
+
+type copier struct {
+	// The copies of the pointers and maps copied so far, to keep shared references shared, and to handle cycles.
+	copies map[typedPointer]reflect.Value
+	// Whether to zero fields tagged `dynconf:"secret"` instead of copying them.
+	redactSecrets bool
+}
+
+func (copier *copier) copy(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+
+		key := typedPointer{pointer: value.Pointer(), valueType: value.Type()}
+		if copied, found := copier.copies[key]; found {
+			return copied
+		}
+
+		copied := reflect.New(value.Type().Elem())
+		copier.copies[key] = copied
+		copied.Elem().Set(copier.copy(value.Elem()))
+		return copied
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(copier.copy(value.Elem()))
+		return copied
+
+	case reflect.Struct:
+		// Start from a shallow copy, so that fields which can't be set are kept as is.
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			switch {
+			case !copied.Field(i).CanSet():
+			case copier.redactSecrets && hasTagValue(value.Type().Field(i), dynconfSecretTagValue):
+				copied.Field(i).SetZero()
+			default:
+				copied.Field(i).Set(copier.copy(value.Field(i)))
+			}
+		}
+		return copied
+
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+
+		key := typedPointer{pointer: value.Pointer(), valueType: value.Type()}
+		if copied, found := copier.copies[key]; found {
+			return copied
+		}
+
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		copier.copies[key] = copied
+		for entries := value.MapRange(); entries.Next(); {
+			copied.SetMapIndex(entries.Key(), copier.copy(entries.Value()))
+		}
+		return copied
+
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(copier.copy(value.Index(i)))
+		}
+		return copied
+
+	case reflect.Array:
+		copied := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(copier.copy(value.Index(i)))
+		}
+		return copied
+
+	default:
+		return value
+	}
+}
+
+// Compute a fingerprint of the value, which changes if the value, or anything it references, is modified.
+func fingerprint(value reflect.Value) uint64 {
+	fingerprinter := fingerprinter{visited: make(map[typedPointer]bool)}
+	fingerprinter.write(value)
+	return fingerprinter.sum
+}
+
+type fingerprinter struct {
+	sum uint64
+	// The pointers and maps being fingerprinted, which are only fingerprinted again by their address, to handle cycles.
+	visited map[typedPointer]bool
+}
+
+func (fingerprinter *fingerprinter) add(value uint64) {
+	hash := fnv.New64a()
+	_, _ = hash.Write(binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, fingerprinter.sum), value))
+	fingerprinter.sum = hash.Sum64()
+}
+
+func (fingerprinter *fingerprinter) addString(value string) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(value))
+	fingerprinter.add(hash.Sum64())
+}
+
+func (fingerprinter *fingerprinter) write(value reflect.Value) {
+	if !value.IsValid() {
+		fingerprinter.add(0)
+		return
+	}
+
+	fingerprinter.add(uint64(value.Kind()))
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			fingerprinter.add(0)
+			return
+		}
+
+		key := typedPointer{pointer: value.Pointer(), valueType: value.Type()}
+		fingerprinter.add(uint64(key.pointer))
+		if fingerprinter.visited[key] {
+			return
+		}
+		fingerprinter.visited[key] = true
+		defer delete(fingerprinter.visited, key)
+		fingerprinter.write(value.Elem())
+
+	case reflect.Interface:
+		if value.IsNil() {
+			fingerprinter.add(0)
+			return
+		}
+
+		fingerprinter.addString(value.Elem().Type().String())
+		fingerprinter.write(value.Elem())
+
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			fingerprinter.write(value.Field(i))
+		}
+
+	case reflect.Map:
+		key := typedPointer{pointer: value.Pointer(), valueType: value.Type()}
+		fingerprinter.add(uint64(key.pointer))
+		fingerprinter.add(uint64(value.Len()))
+		if fingerprinter.visited[key] {
+			return
+		}
+		fingerprinter.visited[key] = true
+		defer delete(fingerprinter.visited, key)
+
+		// Map iteration order is random, so the fingerprints of the entries are combined in an order-independent way.
+		var entriesSum uint64
+		for entries := value.MapRange(); entries.Next(); {
+			entry := *fingerprinter
+			entry.sum = 0
+			entry.write(entries.Key())
+			entry.write(entries.Value())
+			entriesSum += entry.sum
+		}
+		fingerprinter.add(entriesSum)
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice {
+			fingerprinter.add(uint64(value.Pointer()))
+		}
+		fingerprinter.add(uint64(value.Len()))
+		for i := 0; i < value.Len(); i++ {
+			fingerprinter.write(value.Index(i))
+		}
+
+	case reflect.Bool:
+		if value.Bool() {
+			fingerprinter.add(1)
+		} else {
+			fingerprinter.add(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fingerprinter.add(uint64(value.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		fingerprinter.add(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		fingerprinter.add(math.Float64bits(value.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		fingerprinter.add(math.Float64bits(real(value.Complex())))
+		fingerprinter.add(math.Float64bits(imag(value.Complex())))
+	case reflect.String:
+		fingerprinter.addString(value.String())
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		fingerprinter.add(uint64(value.Pointer()))
+	}
+}
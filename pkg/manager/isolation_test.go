@@ -0,0 +1,130 @@
+package manager_test
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+// Copy the collections deeply, as the manager is expected to.
+func copyCollections(cfg testutils.MockConfigurationWithCollections) testutils.MockConfigurationWithCollections {
+	copied := testutils.MockConfigurationWithCollections{
+		Tenants:   maps.Clone(cfg.Tenants),
+		Shards:    slices.Clone(cfg.Shards),
+		Pipelines: make([]*testutils.MockConfigurationPipeline, 0, len(cfg.Pipelines)),
+	}
+	for _, pipeline := range cfg.Pipelines {
+		copiedPipeline := *pipeline
+		copied.Pipelines = append(copied.Pipelines, &copiedPipeline)
+	}
+
+	return copied
+}
+
+func TestConfigurationsAreCopiedByDefault(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithCollections](
+		"testConfigurationsAreCopiedByDefault",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+	expectedConfiguration := copyCollections(mockConfiguration)
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	mockConfiguration.Tenants["mutated"] = testutils.MockConfigurationA{}
+
+	if _, err := mgr.Register([]string{"Tenants"}, func(cfg map[string]testutils.MockConfigurationA) error {
+		cfg["mutated"] = testutils.MockConfigurationA{}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Pipelines"}, func(cfg []*testutils.MockConfigurationPipeline) error {
+		for _, pipeline := range cfg {
+			pipeline.Value += "mutated"
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	var shards []testutils.MockConfigurationB
+	if err := mgr.Get([]string{"Shards"}, &shards); err != nil {
+		t.Fatalf("failed to get configuration: %#v", err)
+	}
+	for i := range shards {
+		shards[i].Value = !shards[i].Value
+	}
+	mgr.Snapshot().Pipelines[0].Value += "mutated"
+
+	if cfg := mgr.Snapshot(); !reflect.DeepEqual(cfg, expectedConfiguration) {
+		t.Fatalf("expected configuration %v after modules mutated their copies, but got %v", expectedConfiguration, cfg)
+	}
+	if preview, err := mgr.PreviewConfigurationUpdate(expectedConfiguration); err != nil || len(preview.Changes) != 0 {
+		t.Fatalf("expected no changes after modules mutated their copies, but got %v (%#v)", preview.Changes, err)
+	}
+}
+
+func TestConfigurationsAreShared(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithCollections](
+		"testConfigurationsAreShared",
+		manager.Options{Isolation: manager.ShareConfigurations},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var pipelines []*testutils.MockConfigurationPipeline
+	if _, err := mgr.Register([]string{"Pipelines"}, func(cfg []*testutils.MockConfigurationPipeline) error {
+		pipelines = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	if pipelines[0] != mockConfiguration.Pipelines[0] || mgr.Snapshot().Pipelines[0] != mockConfiguration.Pipelines[0] {
+		t.Fatalf("expected the configuration to be shared with the module and the snapshot")
+	}
+}
+
+func TestMutationsDetected(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithCollections](
+		"testMutationsDetected",
+		manager.Options{Isolation: manager.DetectMutations},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithCollections()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if _, err := mgr.Register([]string{"Tenants"}, func(cfg map[string]testutils.MockConfigurationA) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register a callback which doesn't mutate its configuration: %#v", err)
+	}
+
+	_, err = mgr.Register([]string{"Pipelines"}, func(cfg []*testutils.MockConfigurationPipeline) error {
+		cfg[0].Value += "mutated"
+		return nil
+	})
+	if !errors.Is(err, manager.ErrConfigurationMutated) {
+		t.Fatalf("wrong error when registering a callback which mutates its configuration: %#v", err)
+	}
+}
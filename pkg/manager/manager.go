@@ -1,11 +1,15 @@
 package manager
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	metrics_factory "github.com/groundcover-com/metrics/pkg/factory"
 	metrics_types "github.com/groundcover-com/metrics/pkg/types"
@@ -13,17 +17,32 @@ import (
 
 const (
 	pathSeparator = "."
-
-	managerMetricPrefix = "dynconf_manager_"
-	errorMetricName     = managerMetricPrefix + "error"
-	errorMetricKey      = "error"
-	idMetricKey         = "id"
+	// Separates the elements of a path's key, which unlike its string can't be shared by different paths, as path
+	// elements, such as map keys, may contain the path separator but not this.
+	pathKeySeparator = "\x00"
+
+	managerMetricPrefix        = "dynconf_manager_"
+	errorMetricName            = managerMetricPrefix + "error"
+	pendingRestartMetricName   = managerMetricPrefix + "pending_restart_paths"
+	updateSupersededMetricName = managerMetricPrefix + "update_superseded"
+	errorMetricKey             = "error"
+	idMetricKey                = "id"
 )
 
 var (
 	// Registering to listen on updates to a path that doesn't exist within the configuration will return this error.
 	ErrNoMatchingFieldFound = errors.New("no matching field found")
 
+	// Paths may select entries of maps by their keys, and elements of slices and arrays by their indices. If the
+	// elements are structs with a field tagged `dynconf:"key"`, they are selected by the value of that field instead.
+	// Registering to listen on updates to an entry that doesn't exist within the configuration will return this error.
+	// Once registered, if an update removes the entry, the registration isn't called until the entry exists again.
+	ErrNoMatchingEntryFound = errors.New("no matching entry found")
+
+	// Wildcard paths select each entry of a map, a slice or an array by its key, so each entry must have a unique key
+	// which can be given as a path element. Otherwise, selecting the entries returns this error.
+	ErrInvalidEntryKey = errors.New("invalid entry key")
+
 	// The configuration manager allows only specific types of configurations to be used. This error indicates that a
 	// wrong configuration type is used, and it can only be returned on the first configuration update.
 	ErrWrongConfigurationType = errors.New("wrong configuration type")
@@ -38,6 +57,40 @@ var (
 
 	// When registering, a valid path must be provided.
 	ErrInvalidPath = errors.New("invalid path")
+
+	// When registering, the dependencies of all registrations must not form a cycle. If they do, this error is
+	// returned.
+	ErrDependencyCycle = errors.New("dependency cycle")
+
+	// A callback which doesn't return before its deadline, or before the context of the configuration update is done,
+	// is deemed to reject the configuration with this error. As the callback may still apply the configuration once it
+	// returns, its module is restored as well, in the background after it returns. Until then, calls of the callback
+	// fail with this error at once.
+	ErrCallbackTimeout = errors.New("callback timed out")
+
+	// Rolling back to a version which isn't kept in the configuration history returns this error. Only the most recent
+	// versions are kept, as configured by the history size option.
+	ErrVersionNotFound = errors.New("version not found in history")
+
+	// A configuration update which changes static parts of the configuration is rejected with this error, unless the
+	// static policy option allows it to be applied pending a restart.
+	ErrStaticFieldChanged = errors.New("static field changed")
+
+	// When detecting mutations (see Isolation), a module which modifies the configuration given to it is deemed to
+	// reject the configuration with this error.
+	ErrConfigurationMutated = errors.New("configuration mutated by module")
+
+	// A callback which panics is deemed to reject the configuration with a *PanicError, which matches this error.
+	ErrCallbackPanicked = errors.New("callback panicked")
+
+	// Callbacks can't check a configuration without applying it, unless they are registered with a validation function
+	// (see RegisterOptions.Validate). Validating a configuration update returns this error, wrapped in an
+	// *UpdateError, for each of the changed modules which can't validate it.
+	ErrNotValidated = errors.New("module can't validate configuration without applying it")
+
+	// When coalescing updates, a configuration update which is superseded by a newer one while waiting for the update
+	// in progress isn't applied, and returns this error.
+	ErrUpdateSuperseded = errors.New("configuration update superseded")
 )
 
 type DynamicConfigurationManagerMetrics struct {
@@ -45,10 +98,15 @@ type DynamicConfigurationManagerMetrics struct {
 	newPathConfigurationDoesNotExist   *metrics_types.LazyCounter
 	oldPathConfigurationDoesNotExist   *metrics_types.LazyCounter
 	moduleDoesNotAllowNewConfiguration *metrics_types.LazyCounter
+	staticFieldChanged                 *metrics_types.LazyCounter
+	callbackPanicked                   *metrics_types.LazyCounter
+	updateSuperseded                   *metrics_types.LazyCounter
+	// The number of static paths whose changes are pending a restart, reported by a gauge.
+	pendingRestartPaths atomic.Int64
 }
 
 func NewDynamicConfigurationManagerMetrics(id string) *DynamicConfigurationManagerMetrics {
-	return &DynamicConfigurationManagerMetrics{
+	metrics := &DynamicConfigurationManagerMetrics{
 		failedToRestore: metrics_factory.CreateErrorCounter(
 			errorMetricName,
 			map[string]string{errorMetricKey: "failed_to_restore", idMetricKey: id},
@@ -65,95 +123,485 @@ func NewDynamicConfigurationManagerMetrics(id string) *DynamicConfigurationManag
 			errorMetricName,
 			map[string]string{errorMetricKey: "module_does_not_allow_new_configuration", idMetricKey: id},
 		),
+		staticFieldChanged: metrics_factory.CreateErrorCounter(
+			errorMetricName,
+			map[string]string{errorMetricKey: "static_field_changed", idMetricKey: id},
+		),
+		callbackPanicked: metrics_factory.CreateErrorCounter(
+			errorMetricName,
+			map[string]string{errorMetricKey: "callback_panicked", idMetricKey: id},
+		),
+		updateSuperseded: metrics_factory.CreateInfoCounter(
+			updateSupersededMetricName,
+			map[string]string{idMetricKey: id},
+		),
 	}
+	metrics_factory.CreateWarningGauge(
+		pendingRestartMetricName,
+		map[string]string{idMetricKey: id},
+		func() float64 { return float64(metrics.pendingRestartPaths.Load()) },
+	)
+
+	return metrics
 }
 
 type DynamicConfigurationManager[Configuration any] struct {
-	id  string
-	cfg Configuration
+	id      string
+	cfg     Configuration
+	options Options
+	// The current configuration, published for reading without waiting for configuration updates in progress.
+	snapshot atomic.Pointer[Configuration]
 
 	configUpdateLock sync.Mutex
-	registered       map[string][]registeredConfigurable
+	// The number of configuration updates requested so far, by which updates waiting for the lock are coalesced.
+	requestedUpdates atomic.Uint64
+	// Registered configurables, in the order of registration.
+	registered []*registeredConfigurable
+	// Registered configurables, in the order in which they are called upon a configuration update.
+	ordered []*registeredConfigurable
+	// The changes made by the last applied configuration update, published for reading without waiting for
+	// configuration updates in progress.
+	lastChanges atomic.Pointer[[]Change]
+	// The version of the current configuration, which is incremented upon every applied configuration update, and the
+	// most recent applied configurations, oldest first. Both are published for reading without waiting for
+	// configuration updates in progress.
+	version atomic.Uint64
+	history atomic.Pointer[[]HistoryEntry[Configuration]]
+	// The applied changes of static paths, from their values at startup, which take effect upon a restart, published
+	// for reading without waiting for configuration updates in progress.
+	pendingRestart atomic.Pointer[[]Change]
 
 	metrics *DynamicConfigurationManagerMetrics
 }
 
 func NewDynamicConfigurationManager[Configuration any](id string) (*DynamicConfigurationManager[Configuration], error) {
+	return NewDynamicConfigurationManagerWithOptions[Configuration](id, Options{})
+}
+
+func NewDynamicConfigurationManagerWithOptions[Configuration any](
+	id string,
+	options Options,
+) (*DynamicConfigurationManager[Configuration], error) {
 	if err := validateConfigurationType[Configuration](); err != nil {
 		return nil, err
 	}
 
-	return &DynamicConfigurationManager[Configuration]{
-		registered: make(map[string][]registeredConfigurable),
+	mgr := &DynamicConfigurationManager[Configuration]{
+		registered: make([]*registeredConfigurable, 0),
+		ordered:    make([]*registeredConfigurable, 0),
 		id:         id,
+		options:    options,
 		metrics:    NewDynamicConfigurationManagerMetrics(id),
-	}, nil
+	}
+	var zeroConfiguration Configuration
+	mgr.snapshot.Store(&zeroConfiguration)
+	mgr.history.Store(&[]HistoryEntry[Configuration]{})
+	mgr.pendingRestart.Store(&[]Change{})
+
+	// Static paths are only compared upon updates, so unknown paths, e.g. with typos, would never be deemed changed.
+	for _, path := range options.StaticPaths {
+		if err := mgr.resolveTypePath(reflect.TypeFor[Configuration](), path); err != nil {
+			return nil, fmt.Errorf("invalid static path %s: %w", pathToString(path), err)
+		}
+	}
+
+	return mgr, nil
 }
 
 // Pass updated configuration to the configuration manager.
 // Before calling that, the configuration is the zero configuration, so it's good practice to call this for the first
 // time right after initiating the manager.
-func (mgr *DynamicConfigurationManager[Configuration]) OnConfigurationUpdate(
+// If a registered module rejects the new configuration, the returned error is an *UpdateError.
+func (mgr *DynamicConfigurationManager[Configuration]) OnConfigurationUpdate(newConfiguration Configuration) error {
+	return mgr.OnConfigurationUpdateContext(context.Background(), newConfiguration)
+}
+
+// Same as OnConfigurationUpdate, where the given context is passed to the callbacks. The context may also carry the
+// source of the configuration (see WithSource), which is recorded in the configuration history.
+// If the context is done before a callback returns, the callback is deemed to reject the configuration, and
+// restoration occurs. Restoration itself isn't affected by the context being done.
+// When coalescing updates, an update which is superseded while waiting for the update in progress returns
+// ErrUpdateSuperseded without being applied.
+func (mgr *DynamicConfigurationManager[Configuration]) OnConfigurationUpdateContext(
+	ctx context.Context,
 	newConfiguration Configuration,
 ) (finalError error) {
+	// The caller may keep modifying the new configuration, which is then shared with the manager unless it is copied.
+	if mgr.options.Isolation == CopyConfigurations {
+		newConfiguration = deepCopy(reflect.ValueOf(newConfiguration)).Interface().(Configuration)
+	}
+
+	request := mgr.requestedUpdates.Add(1)
+
+	// The observers are notified of the outcome of the update after the lock is released, so that they may call the
+	// manager, e.g. to get the version or to roll back.
+	var notifyOutcome func(observer Observer)
 	mgr.configUpdateLock.Lock()
-	defer mgr.configUpdateLock.Unlock()
+	defer func() {
+		mgr.removeUnsubscribed()
+		mgr.configUpdateLock.Unlock()
+
+		if notifyOutcome != nil {
+			mgr.notify(notifyOutcome)
+		}
+	}()
+
+	source := SourceFromContext(ctx)
+	started := time.Now()
+	if mgr.options.CoalesceUpdates && mgr.requestedUpdates.Load() != request {
+		mgr.metrics.updateSuperseded.Inc()
+		event := UpdateEvent{Source: source, Started: started, Version: mgr.version.Load(), Err: ErrUpdateSuperseded}
+		notifyOutcome = func(observer Observer) { observer.OnUpdateSuperseded(event) }
+		return ErrUpdateSuperseded
+	}
+
+	mgr.notify(func(observer Observer) {
+		observer.OnUpdateStarted(UpdateEvent{Source: source, Started: started, Version: mgr.version.Load()})
+	})
+	changes, staticChanges := mgr.diff(mgr.cfg, newConfiguration)
 
-	configurationsToRestore := make([]any, 0)
-	modulesToRestore := make([]registeredConfigurable, 0)
+	configurationsToRestore := make([]pathConfigurations, 0)
+	modulesToRestore := make([]*registeredConfigurable, 0)
 	defer func() {
 		if finalError == nil {
 			return
 		}
 
-		for i := range modulesToRestore {
-			if err := modulesToRestore[i].call(configurationsToRestore[i]); err != nil {
+		// Restore in reverse order, so that modules are restored after the modules that depend on them.
+		restoreFailures := make([]error, 0)
+		for i := len(modulesToRestore) - 1; i >= 0; i-- {
+			// A module that unsubscribed during the update doesn't need to be restored.
+			if modulesToRestore[i].unsubscribed.Load() {
+				continue
+			}
+
+			restoreStarted := time.Now()
+			if err := modulesToRestore[i].restore(
+				context.WithoutCancel(ctx),
+				configurationsToRestore[i],
+			); err != nil {
 				mgr.metrics.failedToRestore.Inc()
+				restoreFailures = append(restoreFailures, fmt.Errorf(
+					"failed to restore configuration of path %s: %w",
+					modulesToRestore[i].pathString,
+					err,
+				))
+
+				event := newModuleEvent(
+					source,
+					modulesToRestore[i],
+					configurationsToRestore[i],
+					time.Since(restoreStarted),
+					err,
+				)
+				mgr.notify(func(observer Observer) { observer.OnRestoreFailed(event) })
 			}
 		}
+
+		var updateError *UpdateError
+		if errors.As(finalError, &updateError) {
+			updateError.RestoreFailures = restoreFailures
+		}
+
+		event := UpdateEvent{
+			Source:   source,
+			Started:  started,
+			Duration: time.Since(started),
+			Version:  mgr.version.Load(),
+			Changes:  changes,
+			Err:      finalError,
+		}
+		notifyOutcome = func(observer Observer) { observer.OnUpdateRolledBack(event) }
 	}()
 
-	for pathStr, registeredConfigurables := range mgr.registered {
-		path := stringToPath(pathStr)
+	if err := mgr.checkStaticChanges(staticChanges); err != nil {
+		return err
+	}
+
+	changedConfigurables, changedConfigurations, err := mgr.getChangedConfigurables(newConfiguration, false)
+	if err != nil {
+		return err
+	}
+	mgr.notifyChangedPaths(source, changedConfigurables, changedConfigurations)
+	changedConfigurables, changedConfigurations, committedConfigurables, committedConfigurations := splitAfterCommit(
+		changedConfigurables,
+		changedConfigurations,
+	)
+
+	// Two-phase configurables validate the new configuration before anything is applied, so that they can reject it
+	// without any restoration.
+	if _, err := mgr.dispatch(changedConfigurables, func(i int) error {
+		validateStarted := time.Now()
+		if err := changedConfigurables[i].validate(ctx, changedConfigurations[i]); err != nil {
+			mgr.metrics.moduleDoesNotAllowNewConfiguration.Inc()
+			mgr.notifyModule(source, changedConfigurables[i], changedConfigurations[i], validateStarted, err)
+			return newUpdateError(changedConfigurables[i], changedConfigurations[i], err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
 
-		newPathConfiguration, err := mgr.getStructByPath(newConfiguration, path)
+	// A module whose call timed out may still apply the new configuration, so it's restored as well. Its restoration
+	// waits for the call to return.
+	timedOut := make([]bool, len(changedConfigurables))
+	applied, err := mgr.dispatch(changedConfigurables, func(i int) error {
+		// A module that unsubscribed during the update isn't called, and won't be restored either.
+		if changedConfigurables[i].unsubscribed.Load() {
+			return nil
+		}
+
+		callStarted := time.Now()
+		err := changedConfigurables[i].call(ctx, changedConfigurations[i])
+		mgr.notifyModule(source, changedConfigurables[i], changedConfigurations[i], callStarted, err)
 		if err != nil {
-			mgr.metrics.newPathConfigurationDoesNotExist.Inc()
-			return fmt.Errorf("failed to find new configuration of path %s: %w", path, err)
+			timedOut[i] = errors.Is(err, ErrCallbackTimeout)
+			mgr.metrics.moduleDoesNotAllowNewConfiguration.Inc()
+			return newUpdateError(changedConfigurables[i], changedConfigurations[i], err)
+		}
+		return nil
+	})
+	for i := range changedConfigurables {
+		if timedOut[i] || slices.Contains(applied, i) {
+			configurationsToRestore = append(configurationsToRestore, changedConfigurations[i])
+			modulesToRestore = append(modulesToRestore, changedConfigurables[i])
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	mgr.lastChanges.Store(&changes)
+	mgr.cfg = newConfiguration
+	mgr.snapshot.Store(&newConfiguration)
+	mgr.recordPendingRestart(staticChanges)
+	mgr.version.Add(1)
+	mgr.recordHistory(ctx)
+
+	// Registrations called after commit can't reject the update, so their errors are only reported.
+	for i, configurable := range committedConfigurables {
+		if configurable.unsubscribed.Load() {
+			continue
+		}
+
+		callStarted := time.Now()
+		err := configurable.call(ctx, committedConfigurations[i])
+		mgr.notifyModule(source, configurable, committedConfigurations[i], callStarted, err)
+	}
+
+	event := UpdateEvent{
+		Source:   source,
+		Started:  started,
+		Duration: time.Since(started),
+		Version:  mgr.version.Load(),
+		Changes:  changes,
+	}
+	notifyOutcome = func(observer Observer) { observer.OnUpdateApplied(event) }
+
+	return nil
+}
+
+// Report each of the changed paths to the observers once, even if several modules are registered on it.
+func (mgr *DynamicConfigurationManager[Configuration]) notifyChangedPaths(
+	source string,
+	changedConfigurables []*registeredConfigurable,
+	changedConfigurations []pathConfigurations,
+) {
+	if len(mgr.options.Observers) == 0 {
+		return
+	}
+
+	type changedPath struct{ path, key string }
+	notified := make(map[changedPath]bool, len(changedConfigurables))
+	for i, configurable := range changedConfigurables {
+		changed := changedPath{path: configurable.pathKey, key: changedConfigurations[i].key}
+		if notified[changed] {
+			continue
+		}
+		notified[changed] = true
+
+		event := PathEvent{
+			Source: source,
+			Path:   configurable.pathString,
+			Key:    changed.key,
+			Kind:   changedConfigurations[i].event,
 		}
+		mgr.notify(func(observer Observer) { observer.OnPathChanged(event) })
+	}
+}
 
-		oldPathConfiguration, err := mgr.getStructByPath(mgr.cfg, path)
+// Report to the observers that the module accepted the new configuration, or rejected it if the error isn't nil.
+func (mgr *DynamicConfigurationManager[Configuration]) notifyModule(
+	source string,
+	configurable *registeredConfigurable,
+	configurations pathConfigurations,
+	started time.Time,
+	err error,
+) {
+	event := newModuleEvent(source, configurable, configurations, time.Since(started), err)
+	mgr.notify(func(observer Observer) {
 		if err != nil {
-			mgr.metrics.oldPathConfigurationDoesNotExist.Inc()
-			return fmt.Errorf("failed to find old configuration of path %s: %w", path, err)
+			observer.OnModuleRejected(event)
+		} else {
+			observer.OnModuleAccepted(event)
 		}
+	})
+}
 
-		// Only trigger callbacks if the relevant configuration has changed
-		if reflect.DeepEqual(oldPathConfiguration, newPathConfiguration) {
+// Get the configurables whose configuration is changed by the new configuration, in the order in which they should be
+// called, along with their configurations. Paths whose configuration can't be found are only counted by the error
+// metric if this isn't a preview.
+func (mgr *DynamicConfigurationManager[Configuration]) getChangedConfigurables(
+	newConfiguration Configuration,
+	preview bool,
+) ([]*registeredConfigurable, []pathConfigurations, error) {
+	changedConfigurables := make([]*registeredConfigurable, 0)
+	changedConfigurations := make([]pathConfigurations, 0)
+
+	pathsConfigurations := make(map[string][]pathConfigurations)
+	for _, configurable := range mgr.ordered {
+		if configurable.unsubscribed.Load() {
 			continue
 		}
 
-		for _, configurable := range registeredConfigurables {
-			if err := configurable.call(newPathConfiguration); err != nil {
-				mgr.metrics.moduleDoesNotAllowNewConfiguration.Inc()
-				return fmt.Errorf("registered module doesn't allow new configuration for path %s: %w", path, err)
+		configurations, exists := pathsConfigurations[configurable.pathKey]
+		if !exists {
+			if configurable.wildcard {
+				var err error
+				configurations, err = mgr.getEntriesConfigurations(
+					mgr.cfg,
+					newConfiguration,
+					configurable.path,
+					preview,
+				)
+				if err != nil {
+					return nil, nil, err
+				}
+			} else {
+				configurationsOfPath, err := mgr.getPathConfigurations(
+					mgr.cfg,
+					newConfiguration,
+					configurable.path,
+					preview,
+				)
+				if err != nil {
+					return nil, nil, err
+				}
+				configurations = []pathConfigurations{configurationsOfPath}
 			}
+			pathsConfigurations[configurable.pathKey] = configurations
+		}
 
-			configurationsToRestore = append(configurationsToRestore, oldPathConfiguration)
-			modulesToRestore = append(modulesToRestore, configurable)
+		for _, entryConfigurations := range configurations {
+			// Only trigger callbacks if the relevant configuration has changed
+			if !entryConfigurations.changed || !mgr.changedFor(configurable, entryConfigurations) {
+				continue
+			}
+
+			changedConfigurables = append(changedConfigurables, configurable)
+			changedConfigurations = append(changedConfigurations, entryConfigurations)
 		}
 	}
 
-	mgr.cfg = newConfiguration
+	return changedConfigurables, changedConfigurations, nil
+}
 
-	return nil
+// Split the changed configurables, along with their configurations, to those which are called as part of the
+// configuration update, and those which are called after it is applied (see RegisterOptions.AfterCommit).
+func splitAfterCommit(
+	changedConfigurables []*registeredConfigurable,
+	changedConfigurations []pathConfigurations,
+) ([]*registeredConfigurable, []pathConfigurations, []*registeredConfigurable, []pathConfigurations) {
+	configurables := make([]*registeredConfigurable, 0, len(changedConfigurables))
+	configurations := make([]pathConfigurations, 0, len(changedConfigurations))
+	committedConfigurables := make([]*registeredConfigurable, 0)
+	committedConfigurations := make([]pathConfigurations, 0)
+	for i, configurable := range changedConfigurables {
+		if configurable.options.AfterCommit {
+			committedConfigurables = append(committedConfigurables, configurable)
+			committedConfigurations = append(committedConfigurations, changedConfigurations[i])
+		} else {
+			configurables = append(configurables, configurable)
+			configurations = append(configurations, changedConfigurations[i])
+		}
+	}
+
+	return configurables, configurations, committedConfigurables, committedConfigurations
+}
+
+// The configurations of a single path before and after a configuration update.
+// Either configuration may not exist if the path selects a map entry or a slice element that doesn't exist.
+// For wildcard paths, these are the configurations of a single entry, selected by the key.
+type pathConfigurations struct {
+	key     string
+	event   EntryEvent
+	old     any
+	new     any
+	changed bool
+}
+
+func (mgr *DynamicConfigurationManager[Configuration]) getPathConfigurations(
+	oldConfiguration Configuration,
+	newConfiguration Configuration,
+	path []string,
+	preview bool,
+) (pathConfigurations, error) {
+	newPathConfiguration, err := mgr.getValueByPath(newConfiguration, path)
+	if errors.Is(err, ErrNoMatchingEntryFound) {
+		// The entry was removed, so there is nothing to notify about until it exists again.
+		return pathConfigurations{}, nil
+	}
+	if err != nil {
+		if !preview {
+			mgr.metrics.newPathConfigurationDoesNotExist.Inc()
+		}
+		return pathConfigurations{}, fmt.Errorf(
+			"failed to find new configuration of path %s: %w",
+			pathToString(path),
+			err,
+		)
+	}
+
+	oldPathConfiguration, err := mgr.getValueByPath(oldConfiguration, path)
+	if errors.Is(err, ErrNoMatchingEntryFound) {
+		// The entry was added (or added again after being removed), so it has changed.
+		return pathConfigurations{event: EntryAdded, new: newPathConfiguration, changed: true}, nil
+	}
+	if err != nil {
+		if !preview {
+			mgr.metrics.oldPathConfigurationDoesNotExist.Inc()
+		}
+		return pathConfigurations{}, fmt.Errorf(
+			"failed to find old configuration of path %s: %w",
+			pathToString(path),
+			err,
+		)
+	}
+
+	return pathConfigurations{
+		event:   EntryModified,
+		old:     oldPathConfiguration,
+		new:     newPathConfiguration,
+		changed: !mgr.equal(oldPathConfiguration, newPathConfiguration),
+	}, nil
+}
+
+// Get the current configuration, as of the last applied configuration update.
+// This never waits for a configuration update in progress: until the update is applied, the configuration before it
+// is returned. The returned configuration is isolated like those given to modules (see Options.Isolation), so when
+// copying, the whole configuration is copied; on hot paths, prefer Get, which only copies the part it gets.
+func (mgr *DynamicConfigurationManager[Configuration]) Snapshot() Configuration {
+	return isolate(mgr.options.Isolation, reflect.ValueOf(*mgr.snapshot.Load())).Interface().(Configuration)
 }
 
 // Get the current value of a part of the configuration.
 // To get the current value and also be notified on updates, register instead.
+// This reads the current snapshot of the configuration (see Snapshot), so it never waits for a configuration update
+// in progress. During an update, including from within callbacks, it gets the configuration before the update.
 //
 // The second argument is an out parameter, where the current configuration will be set.
-// The configuration under this path is a struct, and this has to be a pointer a struct of the same type.
+// The configuration under this path may be a struct or a leaf value, such as a string, number, bool, duration or
+// slice, and this has to be a pointer to a value of the same type.
 func (mgr *DynamicConfigurationManager[Configuration]) Get(path []string, out any) error {
 	if out == nil {
 		return fmt.Errorf("%w: out parameter can not be nil", ErrBadType)
@@ -172,18 +620,18 @@ func (mgr *DynamicConfigurationManager[Configuration]) Get(path []string, out an
 	if err := validatePath(path); err != nil {
 		return err
 	}
+	if _, _, wildcard := splitWildcardPath(path); wildcard {
+		return fmt.Errorf("%w: can't get configuration of wildcard path", ErrInvalidPath)
+	}
 	pathString := pathToString(path)
 
-	mgr.configUpdateLock.Lock()
-	defer mgr.configUpdateLock.Unlock()
-
-	pathConfiguration, err := mgr.getStructByPath(mgr.cfg, path)
+	pathValue, err := mgr.valueByPath(*mgr.snapshot.Load(), path)
 	if err != nil {
 		return fmt.Errorf("failed to perform query of path %s: %w", pathString, err)
 	}
 
 	outValueType := outValue.Type()
-	confType := reflect.TypeOf(pathConfiguration)
+	confType := pathValue.Type()
 	if !confType.AssignableTo(outValueType) {
 		return fmt.Errorf(
 			"%w: can't get configuration into out parameter of the wrong type %s (expected %s)",
@@ -193,71 +641,268 @@ func (mgr *DynamicConfigurationManager[Configuration]) Get(path []string, out an
 		)
 	}
 
-	outValue.Set(reflect.ValueOf(pathConfiguration))
+	outValue.Set(isolate(mgr.options.Isolation, pathValue))
 	return nil
 }
 
 // Register a callback to be called upon dynamic configuration change.
 //
-// The first argument is the path to the struct requested within the configuration struct.
+// The first argument is the path to the configuration requested within the configuration struct. It may lead to a
+// struct or to a leaf value, such as a string, number, bool, duration or slice.
 //
 // The second argument is the callback. It must be a function that receives a single argument, which is of the correct
-// type of the configuration, and returns a single return value, an error.
+// type of the configuration under the path, and returns a single return value, an error. The callback may also
+// receive a context.Context before the configuration, in which case it is given the context of the configuration
+// update, limited by the callback timeout (see Options and RegisterOptions).
+// The callback may receive the old configuration before the new one, in which case the old configuration is the zero
+// value on the initial call from Register. To tell the initial call apart, the callback may receive a bool before the
+// old configuration, which is true on the initial call (see also IsInitialDelivery).
 // The callback should return an error if the given configuration is invalid. It is possible due to the source of the
 // configuration. For example, a user may provide an invalid string configuration.
 // If one of the registered callbacks returns an error for a configuration update, the update is deemed invalid, and
 // configuration restoration will occur: all of the callbacks that already finished successfully will be called again,
 // with the previous configuration.
 //
+// The path may have a single wildcard element ("*"), selecting all of the entries of a map or all of the elements of a
+// slice or an array. The callback is then called for each entry that changed, and must receive the entry's key (a
+// string) before the configuration. It may also receive the entry's EntryEvent between the key and the configuration,
+// in which case it is also called for removed entries, with their last configuration. Callbacks that don't receive
+// events are only called for added and modified entries.
+//
+// Instead of a callback, a value implementing TwoPhaseConfigurable may be given. Such values first validate the new
+// configuration, and only once all of them agree it is applied.
+//
 // Upon successful registration, the callback is instantly called, from the calling thread and before this function
 // returns, with the most up-to-date configuration available.
 // If no configuration was passed to the manager yet, the most up-to-date configuration is the zero configuration.
-func (mgr *DynamicConfigurationManager[Configuration]) Register(path []string, callback any) error {
+// If this initial call returns an error, the registration is removed and the error is returned.
+//
+// The returned subscription can be used to remove the registration, e.g. when the registered module shuts down.
+//
+// Upon a configuration update, callbacks are called in the order of registration. To control that order, register
+// with options instead.
+func (mgr *DynamicConfigurationManager[Configuration]) Register(path []string, callback any) (*Subscription, error) {
+	return mgr.RegisterWithOptions(path, callback, RegisterOptions{})
+}
+
+// Register a callback to be called upon dynamic configuration change, with options that control how it is called.
+// See Register and RegisterOptions.
+func (mgr *DynamicConfigurationManager[Configuration]) RegisterWithOptions(
+	path []string,
+	callback any,
+	options RegisterOptions,
+) (*Subscription, error) {
 	if err := validatePath(path); err != nil {
-		return err
+		return nil, err
+	}
+	for _, dependency := range options.DependsOn {
+		if err := validatePath(dependency); err != nil {
+			return nil, err
+		}
 	}
+
+	// Fields may be named in several ways, so paths are canonicalized before they are compared with each other.
+	path = mgr.canonicalPath(path)
+	dependsOn := make([][]string, 0, len(options.DependsOn))
+	for _, dependency := range options.DependsOn {
+		dependsOn = append(dependsOn, mgr.canonicalPath(dependency))
+	}
+	options.DependsOn = dependsOn
 	pathString := pathToString(path)
 
 	mgr.configUpdateLock.Lock()
 	defer mgr.configUpdateLock.Unlock()
+	defer mgr.removeUnsubscribed()
+
+	_, _, wildcard := splitWildcardPath(path)
 
 	// Get the most up-to-date configuration after acquiring the lock, so that if further changes follow, the registerer
 	// will always get the updates.
-	pathConfiguration, err := mgr.getStructByPath(mgr.cfg, path)
+	var initialConfigurations []pathConfigurations
+	var expectedType reflect.Type
+	if wildcard {
+		entries, err := mgr.getAddedEntriesConfigurations(mgr.cfg, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to perform initial query of path %s: %w", pathString, err)
+		}
+		initialConfigurations = entries
+
+		expectedType, err = mgr.getEntryType(mgr.cfg, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to perform initial query of path %s: %w", pathString, err)
+		}
+	} else {
+		pathValue, err := mgr.valueByPath(mgr.cfg, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to perform initial query of path %s: %w", pathString, err)
+		}
+		initialConfigurations = []pathConfigurations{{event: EntryAdded, new: pathValue.Interface(), changed: true}}
+		expectedType = pathValue.Type()
+	}
+
+	for _, fields := range slices.Concat(options.Fields, options.IgnoreFields) {
+		if err := mgr.resolveTypePath(expectedType, fields); err != nil {
+			return nil, fmt.Errorf("invalid field mask of path %s: %w", pathString, err)
+		}
+	}
+
+	registeredConfigurable := &registeredConfigurable{
+		path:         slices.Clone(path),
+		pathString:   pathString,
+		pathKey:      pathToKey(path),
+		options:      options,
+		wildcard:     wildcard,
+		configurable: callback,
+		expectedType: expectedType,
+		timeout:      mgr.options.CallbackTimeout,
+		isolation:    mgr.options.Isolation,
+		metrics:      mgr.metrics,
+	}
+	if options.Timeout != 0 {
+		registeredConfigurable.timeout = options.Timeout
+	}
+
+	if callback != nil && reflect.TypeOf(callback).Kind() != reflect.Func {
+		if wildcard {
+			return nil, fmt.Errorf(
+				"%w: can't register non-function on wildcard path %s",
+				ErrBadCallback,
+				pathString,
+			)
+		}
+
+		if err := mgr.validateTwoPhaseConfigurable(callback, expectedType); err != nil {
+			return nil, fmt.Errorf("invalid configurable of path %s: %w", pathString, err)
+		}
+		if options.Validate != nil {
+			return nil, fmt.Errorf(
+				"%w: can't register validation function for two-phase configurable of path %s, which validates "+
+					"configurations by itself",
+				ErrBadCallback,
+				pathString,
+			)
+		}
+		if options.AfterCommit {
+			return nil, fmt.Errorf(
+				"%w: can't register two-phase configurable of path %s after commit, as it may reject configurations",
+				ErrBadCallback,
+				pathString,
+			)
+		}
+
+		configurableValue := reflect.ValueOf(callback)
+		registeredConfigurable.validateMethod = configurableValue.MethodByName("Validate")
+		registeredConfigurable.applyMethod = configurableValue.MethodByName("Apply")
+		registeredConfigurable.rollbackMethod = configurableValue.MethodByName("Rollback")
+	} else {
+		shape, err := mgr.validateCallback(callback, expectedType, wildcard)
+		if err != nil {
+			return nil, fmt.Errorf("invalid callback of path %s: %w", pathString, err)
+		}
+
+		registeredConfigurable.callback = reflect.ValueOf(callback)
+		registeredConfigurable.callbackShape = shape
+
+		if options.Validate != nil && options.AfterCommit {
+			return nil, fmt.Errorf(
+				"%w: can't register validation function of path %s after commit, as it may reject configurations",
+				ErrBadCallback,
+				pathString,
+			)
+		}
+		if options.Validate != nil {
+			validatorShape, err := mgr.validateCallback(options.Validate, expectedType, wildcard)
+			if err == nil && (validatorShape.receivesEvent || validatorShape.receivesOld) {
+				err = fmt.Errorf(
+					"%w: can't register validation function which doesn't receive only the new configuration",
+					ErrBadCallback,
+				)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid validation function of path %s: %w", pathString, err)
+			}
+
+			registeredConfigurable.validator = reflect.ValueOf(options.Validate)
+			registeredConfigurable.validatorShape = validatorShape
+		}
+	}
+
+	registered := append(slices.Clone(mgr.registered), registeredConfigurable)
+	ordered, err := orderConfigurables(registered)
 	if err != nil {
-		return fmt.Errorf("failed to perform initial query of path %s: %w", pathString, err)
+		return nil, fmt.Errorf("can't register on path %s: %w", pathString, err)
 	}
-	expectedType := reflect.TypeOf(pathConfiguration)
+	mgr.registered = registered
+	mgr.ordered = ordered
+
+	ctx := withInitialDelivery(context.Background())
+	for _, configurations := range initialConfigurations {
+		if err := registeredConfigurable.validate(ctx, configurations); err != nil {
+			registeredConfigurable.unsubscribed.Store(true)
+			return nil, err
+		}
 
-	if _, exists := mgr.registered[pathString]; !exists {
-		mgr.registered[pathString] = make([]registeredConfigurable, 0)
+		if err := registeredConfigurable.call(ctx, configurations); err != nil {
+			registeredConfigurable.unsubscribed.Store(true)
+			return nil, err
+		}
 	}
 
-	if err := mgr.validateCallback(callback, reflect.TypeOf(pathConfiguration)); err != nil {
-		return fmt.Errorf("invalid callback of path %s: %w", pathString, err)
+	return NewSubscription(func() { mgr.unsubscribe(registeredConfigurable) }), nil
+}
+
+// Mark the registration as removed, so that it is never called again, and remove it from the registered
+// configurables if possible.
+// If the configuration update lock is taken, e.g. because this is called from within a callback, the removal is
+// performed by the lock holder before releasing it.
+func (mgr *DynamicConfigurationManager[Configuration]) unsubscribe(configurable *registeredConfigurable) {
+	configurable.unsubscribed.Store(true)
+
+	if !mgr.configUpdateLock.TryLock() {
+		return
 	}
+	defer mgr.configUpdateLock.Unlock()
 
-	callbackMethod := reflect.ValueOf(callback)
+	mgr.removeUnsubscribed()
+}
 
-	registeredConfigurable := registeredConfigurable{
-		configurable: callback,
-		expectedType: expectedType,
-		callback:     callbackMethod,
+// Remove all of the registrations which were marked as removed. Must be called while holding the configuration update
+// lock.
+func (mgr *DynamicConfigurationManager[Configuration]) removeUnsubscribed() {
+	isUnsubscribed := func(configurable *registeredConfigurable) bool { return configurable.unsubscribed.Load() }
+
+	if !slices.ContainsFunc(mgr.registered, isUnsubscribed) {
+		return
 	}
 
-	mgr.registered[pathString] = append(mgr.registered[pathString], registeredConfigurable)
+	// Removing registrations can't introduce dependency cycles, so the order is kept as is.
+	mgr.registered = slices.DeleteFunc(mgr.registered, isUnsubscribed)
+	mgr.ordered = slices.DeleteFunc(mgr.ordered, isUnsubscribed)
+}
+
+// Traverses the configuration using the given path of field names and returns the found value, which may be a struct
+// or a leaf such as a string, number, bool, duration or slice.
+// Field names are resolved as configured by the path tags option. Path elements within maps are map keys, and path
+// elements within slices and arrays are indices, or the values of the elements' key fields (see
+// ErrNoMatchingEntryFound).
+func (mgr *DynamicConfigurationManager[Configuration]) getValueByPath(cfg any, path []string) (any, error) {
+	srcVal, err := mgr.valueByPath(cfg, path)
+	if err != nil {
+		return nil, err
+	}
 
-	return registeredConfigurable.call(pathConfiguration)
+	return srcVal.Interface(), nil
 }
 
-// Traverses the configuration using the given path of field names and returns the found struct.
-func (mgr *DynamicConfigurationManager[Configuration]) getStructByPath(cfg any, path []string) (any, error) {
+// Like getValueByPath, but returns the reflected value, whose type is the static type of the path's configuration
+// even if it is an interface.
+func (mgr *DynamicConfigurationManager[Configuration]) valueByPath(cfg any, path []string) (reflect.Value, error) {
 	srcVal := reflect.ValueOf(cfg)
 
 	for _, field := range path {
-		if srcVal.Kind() == reflect.Ptr {
+		if srcVal.Kind() == reflect.Ptr || srcVal.Kind() == reflect.Interface {
 			if srcVal.IsNil() {
-				return nil, fmt.Errorf(
+				return reflect.Value{}, fmt.Errorf(
 					"field %s of path %s is nil: %w",
 					field,
 					pathToString(path),
@@ -267,8 +912,36 @@ func (mgr *DynamicConfigurationManager[Configuration]) getStructByPath(cfg any,
 			srcVal = srcVal.Elem()
 		}
 
-		if srcVal.Kind() != reflect.Struct {
-			return nil, fmt.Errorf(
+		switch srcVal.Kind() {
+		case reflect.Struct:
+			fieldVal, found := mgr.fieldByName(srcVal, field)
+			if !found {
+				return reflect.Value{}, fmt.Errorf(
+					"field %s does not exist in struct type %s with path %s: %w",
+					field,
+					srcVal.Type(),
+					pathToString(path),
+					ErrNoMatchingFieldFound,
+				)
+			}
+			srcVal = fieldVal
+
+		case reflect.Map, reflect.Slice, reflect.Array:
+			entryVal, err := entryByKey(srcVal, field)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf(
+					"entry %s does not exist in %s type %s with path %s: %w",
+					field,
+					srcVal.Kind(),
+					srcVal.Type(),
+					pathToString(path),
+					err,
+				)
+			}
+			srcVal = entryVal
+
+		default:
+			return reflect.Value{}, fmt.Errorf(
 				"can't access field %s of non-struct type %s in path %s: %w",
 				field,
 				srcVal.Kind(),
@@ -277,24 +950,10 @@ func (mgr *DynamicConfigurationManager[Configuration]) getStructByPath(cfg any,
 			)
 		}
 
-		structType := srcVal.Type()
-		_, found := structType.FieldByName(field)
-		if !found {
-			return nil, fmt.Errorf(
-				"field %s does not exist in struct type %s with path %s: %w",
-				field,
-				structType,
-				pathToString(path),
-				ErrNoMatchingFieldFound,
-			)
-		}
-
-		srcVal = srcVal.FieldByName(field)
-
 		if srcVal.Kind() == reflect.Ptr {
 			srcVal = srcVal.Elem()
 			if !srcVal.IsValid() {
-				return nil, fmt.Errorf(
+				return reflect.Value{}, fmt.Errorf(
 					"nil pointer encountered at field %s of path %s: %w",
 					field,
 					pathToString(path),
@@ -304,28 +963,80 @@ func (mgr *DynamicConfigurationManager[Configuration]) getStructByPath(cfg any,
 		}
 	}
 
-	return srcVal.Interface(), nil
+	return srcVal, nil
 }
 
+// Validates the callback's signature, and returns the arguments it receives before the configuration.
+// Callbacks may receive a context first. Callbacks of wildcard paths receive the entry's key, and optionally the
+// entry's event, before the configuration.
 func (mgr *DynamicConfigurationManager[Configuration]) validateCallback(
 	callback any,
 	expectedArgType reflect.Type,
-) error {
+	wildcard bool,
+) (callbackShape, error) {
 	callbackType := reflect.TypeOf(callback)
-	if callbackType.Kind() != reflect.Func {
-		return fmt.Errorf("%w: can't register non-function", ErrBadCallback)
+	if callbackType == nil || callbackType.Kind() != reflect.Func {
+		return callbackShape{}, fmt.Errorf("%w: can't register non-function", ErrBadCallback)
 	}
 
-	if callbackType.NumIn() != 1 {
-		return fmt.Errorf(
-			"%w: can't register type whose callback does not receive exactly one argument",
+	var shape callbackShape
+	argIndex := 0
+	if callbackType.NumIn() > 0 && callbackType.In(0) == contextType {
+		shape.receivesContext = true
+		argIndex++
+	}
+
+	if wildcard {
+		if callbackType.NumIn() <= argIndex || callbackType.In(argIndex) != stringType {
+			return callbackShape{}, fmt.Errorf(
+				"%w: can't register callback of wildcard path which doesn't receive the entry's key",
+				ErrBadCallback,
+			)
+		}
+		shape.receivesKey = true
+		argIndex++
+
+		if callbackType.NumIn() > argIndex && callbackType.In(argIndex) == entryEventType {
+			shape.receivesEvent = true
+			argIndex++
+		}
+	}
+
+	// The callback receives either the new configuration, or the old and new configurations, optionally after whether
+	// this is the initial delivery.
+	if callbackType.NumIn()-argIndex == 3 && callbackType.In(argIndex) == boolType {
+		shape.receivesInitial = true
+		argIndex++
+	}
+
+	switch callbackType.NumIn() - argIndex {
+	case 1:
+		if shape.receivesInitial {
+			return callbackShape{}, fmt.Errorf(
+				"%w: can't register type whose callback receives whether this is the initial delivery, but not the "+
+					"old configuration",
+				ErrBadCallback,
+			)
+		}
+	case 2:
+		if callbackType.In(argIndex) != callbackType.In(argIndex+1) {
+			return callbackShape{}, fmt.Errorf(
+				"%w: can't register type whose callback receives old and new configurations of different types",
+				ErrBadCallback,
+			)
+		}
+		shape.receivesOld = true
+	default:
+		return callbackShape{}, fmt.Errorf(
+			"%w: can't register type whose callback does not receive one configuration argument, or two for the "+
+				"old and new configurations",
 			ErrBadCallback,
 		)
 	}
 
-	argType := callbackType.In(0)
+	argType := callbackType.In(callbackType.NumIn() - 1)
 	if !expectedArgType.AssignableTo(argType) {
-		return fmt.Errorf(
+		return callbackShape{}, fmt.Errorf(
 			"%w: can't register type whose callback argument is the wrong type %s (expected %s)",
 			ErrBadCallback,
 			argType.String(),
@@ -334,11 +1045,86 @@ func (mgr *DynamicConfigurationManager[Configuration]) validateCallback(
 	}
 
 	if callbackType.NumOut() != 1 {
-		return fmt.Errorf("%w: can't register type whose callback does not return exactly one argument", ErrBadCallback)
+		return callbackShape{}, fmt.Errorf(
+			"%w: can't register type whose callback does not return exactly one argument",
+			ErrBadCallback,
+		)
 	}
 
 	if callbackType.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
-		return fmt.Errorf("%w: can't register type whose callback does not return an error", ErrBadCallback)
+		return callbackShape{}, fmt.Errorf(
+			"%w: can't register type whose callback does not return an error",
+			ErrBadCallback,
+		)
+	}
+
+	return shape, nil
+}
+
+func (mgr *DynamicConfigurationManager[Configuration]) validateTwoPhaseConfigurable(
+	configurable any,
+	expectedArgType reflect.Type,
+) error {
+	configurableValue := reflect.ValueOf(configurable)
+
+	validateMethod := configurableValue.MethodByName("Validate")
+	applyMethod := configurableValue.MethodByName("Apply")
+	if !validateMethod.IsValid() || !applyMethod.IsValid() {
+		return fmt.Errorf(
+			"%w: can't register non-function which doesn't have both Validate and Apply methods",
+			ErrBadCallback,
+		)
+	}
+
+	if err := validateMethodSignature(validateMethod.Type(), "Validate", expectedArgType, true); err != nil {
+		return err
+	}
+
+	if err := validateMethodSignature(applyMethod.Type(), "Apply", expectedArgType, false); err != nil {
+		return err
+	}
+
+	if rollbackMethod := configurableValue.MethodByName("Rollback"); rollbackMethod.IsValid() {
+		if err := validateMethodSignature(rollbackMethod.Type(), "Rollback", expectedArgType, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validates that a method of a two-phase configurable receives a single argument of the configuration type, and
+// returns either nothing or an error.
+func validateMethodSignature(
+	methodType reflect.Type,
+	name string,
+	expectedArgType reflect.Type,
+	returnsError bool,
+) error {
+	if methodType.NumIn() != 1 {
+		return fmt.Errorf("%w: %s method does not receive exactly one argument", ErrBadCallback, name)
+	}
+
+	argType := methodType.In(0)
+	if !expectedArgType.AssignableTo(argType) {
+		return fmt.Errorf(
+			"%w: %s method argument is the wrong type %s (expected %s)",
+			ErrBadCallback,
+			name,
+			argType.String(),
+			expectedArgType.String(),
+		)
+	}
+
+	if !returnsError {
+		if methodType.NumOut() != 0 {
+			return fmt.Errorf("%w: %s method must not return anything", ErrBadCallback, name)
+		}
+		return nil
+	}
+
+	if methodType.NumOut() != 1 || methodType.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
+		return fmt.Errorf("%w: %s method does not return exactly an error", ErrBadCallback, name)
 	}
 
 	return nil
@@ -355,12 +1141,18 @@ func validateConfigurationType[Configuration any]() error {
 	return nil
 }
 
+// Validate the path given to the manager. Path elements may contain the path separator, e.g. map keys such as
+// "acme.com", as paths are only joined by it for display, and never split by it.
 func validatePath(path []string) error {
+	wildcards := 0
 	for _, p := range path {
-		if strings.Contains(p, pathSeparator) {
-			return ErrInvalidPath
+		if p == wildcardPathElement {
+			wildcards++
 		}
 	}
+	if wildcards > 1 {
+		return fmt.Errorf("%w: path can't have more than one wildcard", ErrInvalidPath)
+	}
 
 	return nil
 }
@@ -369,10 +1161,7 @@ func pathToString(path []string) string {
 	return strings.Join(path, pathSeparator)
 }
 
-func stringToPath(str string) []string {
-	if str == "" {
-		return make([]string, 0)
-	}
-
-	return strings.Split(str, pathSeparator)
+// Identify the path, unlike pathToString, which is only for display.
+func pathToKey(path []string) string {
+	return strings.Join(path, pathKeySeparator)
 }
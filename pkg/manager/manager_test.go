@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/groundcover-com/dynconf/internal/testutils"
 	"github.com/groundcover-com/dynconf/pkg/manager"
@@ -54,13 +56,13 @@ func TestConfigurationWithDuplicates(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"A"}, callbackA); err != nil {
+	if _, err := mgr.Register([]string{"A"}, callbackA); err != nil {
 		t.Fatalf("failed to register mock configuration A: %#v", err)
 	}
-	if err := mgr.Register([]string{"A2"}, callbackA2); err != nil {
+	if _, err := mgr.Register([]string{"A2"}, callbackA2); err != nil {
 		t.Fatalf("failed to register mock configuration A2: %#v", err)
 	}
-	if err := mgr.Register([]string{"B"}, callbackB); err != nil {
+	if _, err := mgr.Register([]string{"B"}, callbackB); err != nil {
 		t.Fatalf("failed to register mock configuration B: %#v", err)
 	}
 
@@ -106,13 +108,13 @@ func TestConfigurationWithTypedef(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"A4"}, callbackA4); err != nil {
+	if _, err := mgr.Register([]string{"A4"}, callbackA4); err != nil {
 		t.Fatalf("failed to register mock configuration A4: %#v", err)
 	}
-	if err := mgr.Register([]string{"A3"}, callbackA3); err != nil {
+	if _, err := mgr.Register([]string{"A3"}, callbackA3); err != nil {
 		t.Fatalf("failed to register mock configuration A3: %#v", err)
 	}
-	if err := mgr.Register([]string{"A2"}, callbackA2); err != nil {
+	if _, err := mgr.Register([]string{"A2"}, callbackA2); err != nil {
 		t.Fatalf("failed to register mock configuration A2: %#v", err)
 	}
 
@@ -143,7 +145,7 @@ func TestRegisterOnPathThatIsNotInTheConfiguration(t *testing.T) {
 		return nil
 	}
 
-	err = mgr.Register([]string{"A4"}, callbackA4)
+	_, err = mgr.Register([]string{"A4"}, callbackA4)
 	if err == nil {
 		t.Fatalf("succeeded registering on path that is not in the configuration")
 	}
@@ -170,7 +172,7 @@ func TestChangeConfigurationAfterInitiatingButBeforeRegistering(t *testing.T) {
 		t.Fatalf("failed to update configuration: %#v", err)
 	}
 
-	if err := mgr.Register([]string{"B"}, callbackB); err != nil {
+	if _, err := mgr.Register([]string{"B"}, callbackB); err != nil {
 		t.Fatalf("failed to register mock configuration: %#v", err)
 	}
 
@@ -248,7 +250,7 @@ func TestRegisterCallbackThatReceivesWrongArgumentType(t *testing.T) {
 		return nil
 	}
 
-	err = mgr.Register([]string{"B"}, callbackA)
+	_, err = mgr.Register([]string{"B"}, callbackA)
 	if err == nil {
 		t.Fatalf("Success error when registering bad callback")
 	}
@@ -302,11 +304,11 @@ func TestRestoration(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"A"}, callbackA); err != nil {
+	if _, err := mgr.Register([]string{"A"}, callbackA); err != nil {
 		t.Fatalf("failed to register mock configuration A: %#v", err)
 	}
 
-	if err := mgr.Register([]string{"B"}, callbackB); err != nil {
+	if _, err := mgr.Register([]string{"B"}, callbackB); err != nil {
 		t.Fatalf("failed to register mock configuration B: %#v", err)
 	}
 
@@ -366,7 +368,7 @@ func TestChangeConfigurationOfTwoTypes(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"A"}, callbackA); err != nil {
+	if _, err := mgr.Register([]string{"A"}, callbackA); err != nil {
 		t.Fatalf("failed to register mock configuration A: %#v", err)
 	}
 
@@ -391,7 +393,7 @@ func TestChangeConfigurationOfTwoTypes(t *testing.T) {
 		)
 	}
 
-	if err := mgr.Register([]string{"B"}, callbackB); err != nil {
+	if _, err := mgr.Register([]string{"B"}, callbackB); err != nil {
 		t.Fatalf("failed to register mock configuration B: %#v", err)
 	}
 
@@ -439,11 +441,11 @@ func TestChangeConfigurationOnlyTriggersAlteredCallbacks(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"A"}, callbackA); err != nil {
+	if _, err := mgr.Register([]string{"A"}, callbackA); err != nil {
 		t.Fatalf("failed to register mock configuration A: %#v", err)
 	}
 
-	if err := mgr.Register([]string{"B"}, callbackB); err != nil {
+	if _, err := mgr.Register([]string{"B"}, callbackB); err != nil {
 		t.Fatalf("failed to register mock configuration B: %#v", err)
 	}
 
@@ -505,18 +507,18 @@ func TestConfigurationWithTwoDepthLevels(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"First", "B"}, callbackFirstA); err == nil ||
+	if _, err := mgr.Register([]string{"First", "B"}, callbackFirstA); err == nil ||
 		!errors.Is(err, manager.ErrBadCallback) {
 		t.Fatalf("wrong error when registering bad callback: %v", err)
 	}
 
-	if err := mgr.Register([]string{"Second"}, callbackSecond); err != nil {
+	if _, err := mgr.Register([]string{"Second"}, callbackSecond); err != nil {
 		t.Fatalf("failed to register callback on Second configuration: %v", err)
 	}
-	if err := mgr.Register([]string{"First", "A"}, callbackFirstA); err != nil {
+	if _, err := mgr.Register([]string{"First", "A"}, callbackFirstA); err != nil {
 		t.Fatalf("failed to register callback on FirstA configuration: %v", err)
 	}
-	if err := mgr.Register([]string{"First", "B"}, callbackFirstB); err != nil {
+	if _, err := mgr.Register([]string{"First", "B"}, callbackFirstB); err != nil {
 		t.Fatalf("failed to register callback on FirstB configuration: %v", err)
 	}
 
@@ -558,15 +560,16 @@ func TestConfigurationWithPointers(t *testing.T) {
 		return nil
 	}
 
-	if err := mgr.Register([]string{"PtrWithA"}, badCallback); err == nil || !errors.Is(err, manager.ErrBadCallback) {
+	if _, err := mgr.Register([]string{"PtrWithA"}, badCallback); err == nil ||
+		!errors.Is(err, manager.ErrBadCallback) {
 		t.Fatalf("wrong error when registering bad callback: %v", err)
 	}
 
-	if err := mgr.Register([]string{"PtrWithA"}, callbackOnPointer); err != nil {
+	if _, err := mgr.Register([]string{"PtrWithA"}, callbackOnPointer); err != nil {
 		t.Fatalf("failed to register callback on pointer configuration: %v", err)
 	}
 
-	if err := mgr.Register([]string{"PtrWithA2", "A"}, callbackWithPointerOnThePath); err != nil {
+	if _, err := mgr.Register([]string{"PtrWithA2", "A"}, callbackWithPointerOnThePath); err != nil {
 		t.Fatalf("failed to register callback on configuration that has pointer on the path: %v", err)
 	}
 
@@ -582,3 +585,562 @@ func TestConfigurationWithPointers(t *testing.T) {
 		)
 	}
 }
+
+func TestUnsubscribe(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testUnsubscribe")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	timesA := 0
+	callbackA := func(cfg testutils.MockConfigurationA) error {
+		timesA++
+		return nil
+	}
+
+	subscription, err := mgr.Register([]string{"A"}, callbackA)
+	if err != nil {
+		t.Fatalf("failed to register mock configuration A: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if timesA != 2 {
+		t.Fatalf("callback A called wrong number of times %d before unsubscribing", timesA)
+	}
+
+	if err := subscription.Close(); err != nil {
+		t.Fatalf("failed to close subscription: %#v", err)
+	}
+	subscription.Unsubscribe()
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration after unsubscribing: %#v", err)
+	}
+
+	if timesA != 2 {
+		t.Fatalf("callback A called after unsubscribing")
+	}
+}
+
+func TestUnsubscribeFromWithinCallback(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testUnsubscribeWithinCallback")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var subscription *manager.Subscription
+	timesA := 0
+	callbackA := func(cfg testutils.MockConfigurationA) error {
+		timesA++
+		if timesA > 1 {
+			subscription.Unsubscribe()
+		}
+		return nil
+	}
+	timesA2 := 0
+	callbackA2 := func(cfg testutils.MockConfigurationA) error {
+		timesA2++
+		return nil
+	}
+
+	subscription, err = mgr.Register([]string{"A"}, callbackA)
+	if err != nil {
+		t.Fatalf("failed to register mock configuration A: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"A"}, callbackA2); err != nil {
+		t.Fatalf("failed to register second callback of mock configuration A: %#v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		mockConfiguration.A.Value += "bla"
+		if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+			t.Fatalf("failed to update configuration: %#v", err)
+		}
+	}
+
+	if timesA != 2 {
+		t.Fatalf("callback A called wrong number of times %d (expected %d)", timesA, 2)
+	}
+
+	if timesA2 != 3 {
+		t.Fatalf("second callback A called wrong number of times %d (expected %d)", timesA2, 3)
+	}
+}
+
+func TestUnsubscribeFromAnotherGoroutineDuringUpdate(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testUnsubscribeDuringUpdate")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	origA := mockConfiguration.A
+	inCallback := make(chan struct{})
+	release := make(chan struct{})
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		if cfg == origA {
+			return nil
+		}
+		close(inCallback)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback A: %#v", err)
+	}
+
+	timesB := 0
+	subscription, err := mgr.RegisterWithOptions(
+		[]string{"B"},
+		func(cfg testutils.MockConfigurationB) error {
+			timesB++
+			return nil
+		},
+		manager.RegisterOptions{AfterCommit: true},
+	)
+	if err != nil {
+		t.Fatalf("failed to register callback B: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	result := make(chan error)
+	go func() {
+		result <- mgr.OnConfigurationUpdate(mockConfiguration)
+	}()
+
+	<-inCallback
+	subscription.Unsubscribe()
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if timesB != 1 {
+		t.Fatalf("callback B called %d times, although it was unsubscribed before the update reached it", timesB)
+	}
+}
+
+func TestRegistrationIsRemovedWhenInitialCallFails(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testInitialCallFails")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	timesA := 0
+	callbackA := func(cfg testutils.MockConfigurationA) error {
+		timesA++
+		return errors.ErrUnsupported
+	}
+
+	subscription, err := mgr.Register([]string{"A"}, callbackA)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when initial call fails: %#v", err)
+	}
+	if subscription != nil {
+		t.Fatalf("got subscription although initial call failed")
+	}
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if timesA != 1 {
+		t.Fatalf("callback A called after its initial call failed")
+	}
+}
+
+func TestTypedSubscribeAndGet(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testTypedSubscribeAndGet")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var copyConfiguration testutils.MockConfigurationA
+	_, err = manager.Subscribe(mgr, []string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		copyConfiguration = cfg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe to mock configuration A: %#v", err)
+	}
+
+	if _, err := manager.Subscribe(mgr, []string{"B"}, func(cfg testutils.MockConfigurationA) error {
+		return nil
+	}); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when subscribing with the wrong type: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if !reflect.DeepEqual(copyConfiguration, mockConfiguration.A) {
+		t.Fatalf("after updating configuration, expected %#v but got %#v", mockConfiguration.A, copyConfiguration)
+	}
+
+	gotA, err := manager.GetAs[testutils.MockConfigurationA](mgr, []string{"A"})
+	if err != nil {
+		t.Fatalf("failed to get mock configuration A: %#v", err)
+	}
+	if !reflect.DeepEqual(gotA, mockConfiguration.A) {
+		t.Fatalf("after getting mock configuration A, expected %#v but got %#v", mockConfiguration.A, gotA)
+	}
+
+	if _, err := manager.GetAs[testutils.MockConfigurationA](mgr, []string{"B"}); !errors.Is(err, manager.ErrBadType) {
+		t.Fatalf("wrong error when getting with the wrong type: %#v", err)
+	}
+}
+
+func TestRegisterOnLeaves(t *testing.T) {
+	mockConfiguration := testutils.RandomMockConfigurationWithLeaves()
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithLeaves]("testRegisterOnLeaves")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var logLevel string
+	logLevelCalls := 0
+	if _, err := manager.Subscribe(mgr, []string{"LogLevel"}, func(cfg string) error {
+		logLevel = cfg
+		logLevelCalls++
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on string leaf: %#v", err)
+	}
+
+	var timeout time.Duration
+	if _, err := manager.Subscribe(mgr, []string{"Timeout"}, func(cfg time.Duration) error {
+		timeout = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on duration leaf: %#v", err)
+	}
+
+	var hosts []string
+	if _, err := manager.Subscribe(mgr, []string{"Hosts"}, func(cfg []string) error {
+		hosts = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on slice leaf: %#v", err)
+	}
+
+	var extra any = "unset"
+	if _, err := manager.Subscribe(mgr, []string{"Extra"}, func(cfg any) error {
+		extra = cfg
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register on interface leaf: %#v", err)
+	}
+
+	if logLevel != mockConfiguration.LogLevel || timeout != mockConfiguration.Timeout ||
+		!reflect.DeepEqual(hosts, mockConfiguration.Hosts) || extra != nil {
+		t.Fatalf("after registering, got %s, %s, %v and %v", logLevel, timeout, hosts, extra)
+	}
+
+	mockConfiguration.Timeout += time.Second
+	mockConfiguration.Hosts = append(mockConfiguration.Hosts, "bla")
+	mockConfiguration.Extra = 5
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if logLevelCalls != 1 {
+		t.Fatalf("callback of unchanged leaf was called %d times (expected 1)", logLevelCalls)
+	}
+	if timeout != mockConfiguration.Timeout || !reflect.DeepEqual(hosts, mockConfiguration.Hosts) || extra != 5 {
+		t.Fatalf("after updating, got %s, %v and %v", timeout, hosts, extra)
+	}
+
+	ratio, err := manager.GetAs[float64](mgr, []string{"Ratio"})
+	if err != nil {
+		t.Fatalf("failed to get float leaf: %#v", err)
+	}
+	if ratio != mockConfiguration.Ratio {
+		t.Fatalf("after getting float leaf, expected %v but got %v", mockConfiguration.Ratio, ratio)
+	}
+
+	if _, err := manager.Subscribe(mgr, []string{"Enabled"}, func(cfg string) error {
+		return nil
+	}); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering callback of wrong type on bool leaf: %#v", err)
+	}
+}
+
+func TestUnexportedFieldIsNotFound(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithLeaves](
+		"testUnexportedFieldIsNotFound",
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+	if err := mgr.OnConfigurationUpdate(testutils.RandomMockConfigurationWithLeaves()); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	var secret string
+	if err := mgr.Get([]string{"secret"}, &secret); !errors.Is(err, manager.ErrNoMatchingFieldFound) {
+		t.Fatalf("wrong error when getting unexported field: %#v", err)
+	}
+}
+
+func TestOldAndNewCallback(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testOldAndNewCallback")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([][2]testutils.MockConfigurationA, 0)
+	if _, err := manager.SubscribeChanges(mgr, []string{"A"}, func(old, new testutils.MockConfigurationA) error {
+		calls = append(calls, [2]testutils.MockConfigurationA{old, new})
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register old-and-new callback: %#v", err)
+	}
+
+	initialB := mockConfiguration.B
+	if _, err := mgr.Register([]string{"B"}, func(cfg testutils.MockConfigurationB) error {
+		if cfg != initialB {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	initialA := mockConfiguration.A
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	updatedA := mockConfiguration.A
+	mockConfiguration.A.Value += "bla"
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	expectedCalls := [][2]testutils.MockConfigurationA{
+		{{}, initialA},
+		{initialA, updatedA},
+		{updatedA, mockConfiguration.A},
+		{mockConfiguration.A, updatedA},
+	}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Fatalf("expected calls %v but got %v", expectedCalls, calls)
+	}
+
+	if _, err := mgr.Register(
+		[]string{"A"},
+		func(old testutils.MockConfigurationA, new testutils.MockConfigurationB) error {
+			return nil
+		},
+	); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering old-and-new callback of different types: %#v", err)
+	}
+}
+
+func TestOldAndNewCallbackWithInitial(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testOldAndNewWithInitial")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	initialDeliveries := make([]bool, 0)
+	if _, err := manager.SubscribeChangesWithInitial(
+		mgr,
+		[]string{"A", "Value"},
+		func(initial bool, old, new string) error {
+			initialDeliveries = append(initialDeliveries, initial)
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register old-and-new callback with initial flag: %#v", err)
+	}
+
+	// The second update's old configuration is the zero value, as on the initial delivery.
+	for _, value := range []string{"", "bla"} {
+		mockConfiguration.A.Value = value
+		if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+			t.Fatalf("failed to update configuration: %#v", err)
+		}
+	}
+
+	if !reflect.DeepEqual(initialDeliveries, []bool{true, false, false}) {
+		t.Fatalf("expected only the first delivery to be initial, but got %v", initialDeliveries)
+	}
+
+	if _, err := mgr.Register([]string{"A"}, func(initial bool, cfg testutils.MockConfigurationA) error {
+		return nil
+	}); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering callback with initial flag but without old configuration: %#v", err)
+	}
+}
+
+func TestGetDuringUpdate(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testGetDuringUpdate")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	oldConfiguration := mockConfiguration
+	inCallback := make(chan struct{})
+	release := make(chan struct{})
+	var gotFromCallback testutils.MockConfigurationA
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		if cfg == oldConfiguration.A { // the initial call
+			return nil
+		}
+
+		if err := mgr.Get([]string{"A"}, &gotFromCallback); err != nil {
+			return err
+		}
+		close(inCallback)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	updateResult := make(chan error)
+	go func() {
+		updateResult <- mgr.OnConfigurationUpdate(mockConfiguration)
+	}()
+	<-inCallback
+
+	if gotFromCallback != oldConfiguration.A {
+		t.Fatalf("from within callback, expected %#v but got %#v", oldConfiguration.A, gotFromCallback)
+	}
+
+	var copyConfiguration testutils.MockConfigurationA
+	if err := mgr.Get([]string{"A"}, &copyConfiguration); err != nil {
+		t.Fatalf("failed to get configuration during update: %#v", err)
+	}
+	if copyConfiguration != oldConfiguration.A {
+		t.Fatalf("during update, expected %#v but got %#v", oldConfiguration.A, copyConfiguration)
+	}
+	if snapshot := mgr.Snapshot(); snapshot != oldConfiguration {
+		t.Fatalf("during update, expected snapshot %#v but got %#v", oldConfiguration, snapshot)
+	}
+
+	close(release)
+	if err := <-updateResult; err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	if snapshot := mgr.Snapshot(); snapshot != mockConfiguration {
+		t.Fatalf("after update, expected snapshot %#v but got %#v", mockConfiguration, snapshot)
+	}
+}
+
+// Wait until the given number of configuration updates were requested, e.g. until updates are waiting for the update
+// in progress.
+func waitForRequestedUpdates[Configuration any](
+	t *testing.T,
+	mgr *manager.DynamicConfigurationManager[Configuration],
+	requested uint64,
+) {
+	deadline := time.Now().Add(5 * time.Second)
+	for mgr.RequestedUpdates() < requested {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d configuration updates to be requested, but got %d", requested, mgr.RequestedUpdates())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type supersededObserver struct {
+	manager.BaseObserver
+	superseded atomic.Int32
+}
+
+func (observer *supersededObserver) OnUpdateSuperseded(event manager.UpdateEvent) {
+	observer.superseded.Add(1)
+}
+
+func TestCoalesceUpdates(t *testing.T) {
+	observer := &supersededObserver{}
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testCoalesceUpdates",
+		manager.Options{CoalesceUpdates: true, Observers: []manager.Observer{observer}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	firstConfiguration := mockConfiguration
+	firstConfiguration.A.Value += "first"
+	inCallback := make(chan struct{})
+	release := make(chan struct{})
+	calls := make([]testutils.MockConfigurationA, 0)
+	if _, err := mgr.Register([]string{"A"}, func(cfg testutils.MockConfigurationA) error {
+		calls = append(calls, cfg)
+		if cfg == firstConfiguration.A {
+			close(inCallback)
+			<-release
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	firstResult := make(chan error)
+	go func() {
+		firstResult <- mgr.OnConfigurationUpdate(firstConfiguration)
+	}()
+	<-inCallback
+
+	// Both updates wait for the first one, and the second one is queued after the superseded one.
+	supersededConfiguration := mockConfiguration
+	supersededConfiguration.A.Value += "superseded"
+	supersededResult := make(chan error)
+	go func() {
+		supersededResult <- mgr.OnConfigurationUpdate(supersededConfiguration)
+	}()
+	waitForRequestedUpdates(t, mgr, 3)
+
+	latestConfiguration := mockConfiguration
+	latestConfiguration.A.Value += "latest"
+	latestResult := make(chan error)
+	go func() {
+		latestResult <- mgr.OnConfigurationUpdate(latestConfiguration)
+	}()
+	waitForRequestedUpdates(t, mgr, 4)
+
+	close(release)
+	if err := <-firstResult; err != nil {
+		t.Fatalf("failed to apply the update in progress: %#v", err)
+	}
+	if err := <-supersededResult; !errors.Is(err, manager.ErrUpdateSuperseded) {
+		t.Fatalf("wrong error of a superseded update: %#v", err)
+	}
+	if superseded := observer.superseded.Load(); superseded != 1 {
+		t.Fatalf("expected observers to be notified of 1 superseded update, but got %d", superseded)
+	}
+	if err := <-latestResult; err != nil {
+		t.Fatalf("failed to apply the latest update: %#v", err)
+	}
+
+	expectedCalls := []testutils.MockConfigurationA{mockConfiguration.A, firstConfiguration.A, latestConfiguration.A}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Fatalf("expected calls %v but got %v", expectedCalls, calls)
+	}
+	if snapshot := mgr.Snapshot(); snapshot != latestConfiguration {
+		t.Fatalf("expected the latest configuration %#v but got %#v", latestConfiguration, snapshot)
+	}
+}
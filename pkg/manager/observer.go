@@ -0,0 +1,109 @@
+package manager
+
+import "time"
+
+// Observes the lifecycle of configuration updates, e.g. for logging and alerting.
+// Observers are set in the manager's options, and are called synchronously during configuration updates, so they
+// should return quickly. With parallel callbacks, module events may be reported concurrently.
+// Only OnUpdateApplied, OnUpdateRolledBack and OnUpdateSuperseded are called once the update is over, so only they may
+// call methods of the manager which wait for configuration updates, such as Rollback. The other methods may only call
+// methods which don't wait, such as Version, History, LastChanges, PendingRestart, Snapshot and Get.
+// Embed BaseObserver to only implement some of the methods.
+type Observer interface {
+	// Called when a configuration update starts, before anything is compared or called.
+	OnUpdateStarted(event UpdateEvent)
+	// Called for each registered path, or entry of a wildcard path, whose configuration is changed by the update.
+	OnPathChanged(event PathEvent)
+	// Called when a registered module accepts the new configuration.
+	OnModuleAccepted(event ModuleEvent)
+	// Called when a registered module rejects the new configuration.
+	OnModuleRejected(event ModuleEvent)
+	// Called when the new configuration is applied, with its version and changes.
+	OnUpdateApplied(event UpdateEvent)
+	// Called when the configuration update fails, after the modules that accepted it are restored.
+	OnUpdateRolledBack(event UpdateEvent)
+	// Called when a registered module fails to restore its previous configuration.
+	OnRestoreFailed(event ModuleEvent)
+	// Called when coalescing updates, instead of any other method, when the configuration update is superseded by a
+	// later one before it starts (see Options.CoalesceUpdates).
+	OnUpdateSuperseded(event UpdateEvent)
+}
+
+// An observer which ignores all events. Embed it in observers which only handle some of the events.
+type BaseObserver struct{}
+
+func (BaseObserver) OnUpdateStarted(event UpdateEvent)    {}
+func (BaseObserver) OnPathChanged(event PathEvent)        {}
+func (BaseObserver) OnModuleAccepted(event ModuleEvent)   {}
+func (BaseObserver) OnModuleRejected(event ModuleEvent)   {}
+func (BaseObserver) OnUpdateApplied(event UpdateEvent)    {}
+func (BaseObserver) OnUpdateRolledBack(event UpdateEvent) {}
+func (BaseObserver) OnRestoreFailed(event ModuleEvent)    {}
+func (BaseObserver) OnUpdateSuperseded(event UpdateEvent) {}
+
+// An event of a whole configuration update.
+type UpdateEvent struct {
+	// The source which the configuration update was labeled with (see WithSource).
+	Source string
+	// The time at which the configuration update started.
+	Started time.Time
+	// The duration of the configuration update so far. Zero when the update starts.
+	Duration time.Duration
+	// The version of the configuration: the new version once the update is applied, and the current version otherwise.
+	Version uint64
+	// The changes made by the update once it is applied, or which it would have made once it is rolled back. They must
+	// not be modified.
+	Changes []Change
+	// The error with which the update failed, once it is rolled back or superseded. Usually an *UpdateError.
+	Err error
+}
+
+// An event of a registered path whose configuration is changed by a configuration update.
+type PathEvent struct {
+	// The source which the configuration update was labeled with (see WithSource).
+	Source string
+	Path   string
+	// For wildcard paths, the key of the changed entry.
+	Key string
+	// Whether the configuration under the path was added, modified or removed.
+	Kind EntryEvent
+}
+
+// An event of a registered module during a configuration update.
+type ModuleEvent struct {
+	// The source which the configuration update was labeled with (see WithSource).
+	Source string
+	Path   string
+	// For wildcard paths, the key of the changed entry.
+	Key string
+	// The name of the registration, as given in its options.
+	Name string
+	// The duration of the module's call.
+	Duration time.Duration
+	// The error with which the module rejected the configuration, or failed to restore its previous configuration.
+	Err error
+}
+
+// Notify each of the observers. An observer which panics is skipped, so that it can't fail the configuration update.
+func (mgr *DynamicConfigurationManager[Configuration]) notify(f func(observer Observer)) {
+	for _, observer := range mgr.options.Observers {
+		_ = mgr.metrics.recovered(func() { f(observer) })
+	}
+}
+
+func newModuleEvent(
+	source string,
+	configurable *registeredConfigurable,
+	configurations pathConfigurations,
+	duration time.Duration,
+	err error,
+) ModuleEvent {
+	return ModuleEvent{
+		Source:   source,
+		Path:     configurable.pathString,
+		Key:      configurations.key,
+		Name:     configurable.options.Name,
+		Duration: duration,
+		Err:      err,
+	}
+}
@@ -0,0 +1,251 @@
+package manager_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+type mockObserver struct {
+	manager.BaseObserver
+	events []string
+}
+
+func (observer *mockObserver) OnUpdateStarted(event manager.UpdateEvent) {
+	observer.events = append(observer.events, "started:"+event.Source)
+}
+
+func (observer *mockObserver) OnPathChanged(event manager.PathEvent) {
+	observer.events = append(observer.events, fmt.Sprintf("changed:%s:%s", event.Path, event.Kind))
+}
+
+func (observer *mockObserver) OnModuleAccepted(event manager.ModuleEvent) {
+	observer.events = append(observer.events, "accepted:"+event.Name)
+}
+
+func (observer *mockObserver) OnModuleRejected(event manager.ModuleEvent) {
+	observer.events = append(observer.events, "rejected:"+event.Name)
+}
+
+func (observer *mockObserver) OnUpdateApplied(event manager.UpdateEvent) {
+	observer.events = append(observer.events, fmt.Sprintf("applied:%d:%d", event.Version, len(event.Changes)))
+}
+
+func (observer *mockObserver) OnUpdateRolledBack(event manager.UpdateEvent) {
+	var updateError *manager.UpdateError
+	if errors.As(event.Err, &updateError) {
+		observer.events = append(observer.events, "rolled back:"+updateError.Name)
+	}
+}
+
+func (observer *mockObserver) OnRestoreFailed(event manager.ModuleEvent) {
+	observer.events = append(observer.events, "restore failed:"+event.Name)
+}
+
+func TestObserver(t *testing.T) {
+	observer := &mockObserver{}
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testObserver",
+		manager.Options{Observers: []manager.Observer{observer}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	initialChanges := len(manager.Diff(testutils.MockConfigurationWithOneDepthLevel{}, mockConfiguration))
+
+	calls := 0
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"A"},
+		func(cfg testutils.MockConfigurationA) error {
+			calls++
+			if calls == 4 { // the restoration of the third update
+				return errCantRestore
+			}
+			return nil
+		},
+		manager.RegisterOptions{Name: "moduleA"},
+	); err != nil {
+		t.Fatalf("failed to register first callback: %#v", err)
+	}
+
+	initialB := mockConfiguration.B
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"B"},
+		func(cfg testutils.MockConfigurationB) error {
+			if cfg != initialB {
+				return errors.ErrUnsupported
+			}
+			return nil
+		},
+		manager.RegisterOptions{Name: "moduleB"},
+	); err != nil {
+		t.Fatalf("failed to register second callback: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	ctx := manager.WithSource(context.Background(), "test")
+	if err := mgr.OnConfigurationUpdateContext(ctx, mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration: %#v", err)
+	}
+
+	mockConfiguration.A.Value += "bla"
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	if err := mgr.OnConfigurationUpdateContext(ctx, mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	expectedEvents := []string{
+		"started:",
+		fmt.Sprintf("applied:1:%d", initialChanges),
+		"started:test",
+		"changed:A:modified",
+		"accepted:moduleA",
+		"applied:2:1",
+		"started:test",
+		"changed:A:modified",
+		"changed:B:modified",
+		"accepted:moduleA",
+		"rejected:moduleB",
+		"restore failed:moduleA",
+		"rolled back:moduleB",
+	}
+	if !slices.Equal(observer.events, expectedEvents) {
+		t.Fatalf("expected events %v but got %v", expectedEvents, observer.events)
+	}
+}
+
+func TestUpdateStartedBeforeComparing(t *testing.T) {
+	observer := &mockObserver{}
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithService](
+		"testUpdateStartedBeforeComparing",
+		manager.Options{
+			Observers: []manager.Observer{observer},
+			Comparators: []manager.Comparator{
+				manager.CompareAs(func(oldLimits, newLimits testutils.MockConfigurationLimits) bool {
+					observer.events = append(observer.events, "compared")
+					return oldLimits == newLimits
+				}),
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	if err := mgr.OnConfigurationUpdate(initialServiceConfiguration()); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if len(observer.events) < 2 || observer.events[0] != "started:" || observer.events[1] != "compared" {
+		t.Fatalf("expected the update to start before anything is compared, but got events %v", observer.events)
+	}
+}
+
+// An observer which calls back into the manager, which must not deadlock.
+type callingObserver struct {
+	manager.BaseObserver
+	mgr      *manager.DynamicConfigurationManager[testutils.MockConfigurationWithOneDepthLevel]
+	versions []uint64
+	accepted []bool
+}
+
+func (observer *callingObserver) OnUpdateStarted(event manager.UpdateEvent) {
+	observer.versions = append(observer.versions, observer.mgr.Version())
+}
+
+func (observer *callingObserver) OnUpdateApplied(event manager.UpdateEvent) {
+	observer.versions = append(observer.versions, observer.mgr.Version())
+}
+
+func (observer *callingObserver) OnUpdateRolledBack(event manager.UpdateEvent) {
+	observer.versions = append(observer.versions, observer.mgr.Version())
+
+	// Previewing waits for configuration updates, so it can only be called once the update is over.
+	preview, err := observer.mgr.PreviewConfigurationUpdate(observer.mgr.Snapshot())
+	observer.accepted = append(observer.accepted, err == nil && preview.Accepted())
+}
+
+func TestObserverCallsManager(t *testing.T) {
+	observer := &callingObserver{}
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testObserverCallsManager",
+		manager.Options{Observers: []manager.Observer{observer}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+	observer.mgr = mgr
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	initialB := mockConfiguration.B
+	if _, err := mgr.Register([]string{"B"}, func(cfg testutils.MockConfigurationB) error {
+		if cfg != initialB {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	mockConfiguration.B.Value = !mockConfiguration.B.Value
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	expectedVersions := []uint64{0, 1, 1, 1}
+	if !slices.Equal(observer.versions, expectedVersions) {
+		t.Fatalf("expected observer to get versions %v but got %v", expectedVersions, observer.versions)
+	}
+	if !slices.Equal(observer.accepted, []bool{true}) {
+		t.Fatalf("expected observer to preview current configuration as accepted, but got %v", observer.accepted)
+	}
+}
+
+type panickingObserver struct {
+	manager.BaseObserver
+}
+
+func (panickingObserver) OnUpdateStarted(event manager.UpdateEvent) {
+	panic("can't observe update")
+}
+
+func (panickingObserver) OnUpdateApplied(event manager.UpdateEvent) {
+	panic("can't observe update")
+}
+
+func TestPanickingObserver(t *testing.T) {
+	observer := &mockObserver{}
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithOneDepthLevel](
+		"testPanickingObserver",
+		manager.Options{Observers: []manager.Observer{panickingObserver{}, observer}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := testutils.RandomMockConfigurationWithOneDepthLevel()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration despite a panicking observer: %#v", err)
+	}
+
+	initialChanges := len(manager.Diff(testutils.MockConfigurationWithOneDepthLevel{}, mockConfiguration))
+	expectedEvents := []string{"started:", fmt.Sprintf("applied:1:%d", initialChanges)}
+	if !slices.Equal(observer.events, expectedEvents) {
+		t.Fatalf("expected events %v but got %v", expectedEvents, observer.events)
+	}
+}
@@ -0,0 +1,73 @@
+package manager
+
+import "time"
+
+type Options struct {
+	// The maximum duration of a single call to a registered callback. A callback which doesn't return in time is deemed
+	// to reject the configuration. Zero means no limit. This can be overridden per registration.
+	CallbackTimeout time.Duration
+	// The maximum number of callbacks called concurrently upon a configuration update. Callbacks of registrations whose
+	// paths are disjoint, which have the same priority and which don't depend on each other may be called concurrently,
+	// and all other callbacks are called in order. Zero or one means all callbacks are called one after the other.
+	MaxParallelCallbacks int
+	// Struct tags by which path elements are resolved, in order of priority, e.g. "mapstructure", "yaml" and "json".
+	// With tags configured, a field is named by the first of these tags which names it, and a path element matches
+	// either that name or the Go field name. This way the same names are used in paths as in the configuration file.
+	// By default, only Go field names are used.
+	PathTags []string
+	// The number of most recent applied configurations kept in the configuration history, which can be rolled back to.
+	// Zero means no history is kept.
+	HistorySize int
+	// Observers which are notified of the lifecycle of configuration updates, in order.
+	Observers []Observer
+	// Custom equalities by which changes of values of their types are detected, e.g. for types with funcs or caches,
+	// see CompareAs. Values of other types are compared field by field, skipping fields tagged `dynconf:"ignore"`.
+	Comparators []Comparator
+	// Paths of static parts of the configuration, which are only read at startup and can't change at runtime, e.g.
+	// {"Server", "Port"}. Fields can also be made static by tagging them `dynconf:"static"`. Creating the manager fails
+	// if any of the paths doesn't exist in the configuration type.
+	StaticPaths [][]string
+	// What to do upon configuration updates which change static parts of the configuration, after the first one.
+	// By default, such updates are rejected.
+	StaticPolicy StaticPolicy
+	// How the configurations given to registered modules, and gotten through Get, are isolated from the manager's own
+	// configuration. By default, they are copied.
+	Isolation Isolation
+	// Whether to coalesce configuration updates which wait for the update in progress, so that only the latest of them
+	// is applied. The others return ErrUpdateSuperseded without being applied, and are only reported to observers by
+	// OnUpdateSuperseded.
+	CoalesceUpdates bool
+}
+
+type RegisterOptions struct {
+	// Upon a configuration update, registrations with a higher priority are called before registrations with a lower
+	// priority, and restored after them. Registrations with the same priority are called in the order of registration.
+	Priority int
+	// Paths that this registration depends on. Registrations on these paths, or on paths within them, are always called
+	// before this registration and restored after it, regardless of priority.
+	DependsOn [][]string
+	// The maximum duration of a single call to the registered callback, overriding the manager's callback timeout.
+	Timeout time.Duration
+	// The name of the registered module, by which errors refer to it, see UpdateError.
+	Name string
+	// Paths within the registered path, e.g. {"Limits", "Memory"}, to which change detection is narrowed. If set, the
+	// registration is only called when the configuration within these paths changes. For wildcard paths, these are
+	// paths within each of the entries.
+	Fields [][]string
+	// Paths within the registered path whose changes are ignored, like fields tagged `dynconf:"ignore"`.
+	IgnoreFields [][]string
+	// A custom equality of the old and new configurations of the registered path. If set, the registration is only
+	// called when the configuration changed and this returns false. Added and removed entries are always delivered, as
+	// are configurations whose comparison panics.
+	Equal func(oldConfiguration any, newConfiguration any) bool
+	// A function which checks the configuration without applying it, for registrations of callbacks. It receives the
+	// configuration like a callback which receives only the new configuration, e.g. func(ModuleA) error, optionally
+	// after a context, and for wildcard paths, after the entry's key. If set, it is called along with the validation of
+	// two-phase configurables, before any module is given the new configuration, and by previews.
+	Validate any
+	// Call the registration only once a configuration update is applied, i.e. after all of the other registrations
+	// accepted it, so that it's never given a configuration which is then rejected. Such registrations can't reject
+	// configuration updates, so errors they return are only reported to the observers, and they aren't restored.
+	// Neither two-phase configurables nor validation functions can be registered this way.
+	AfterCommit bool
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Orders the given configurables, which are in the order of registration, in the order in which they should be called
+// upon a configuration update.
+// Configurables are ordered by priority, and then by registration order, as long as all dependencies are satisfied.
+func orderConfigurables(registered []*registeredConfigurable) ([]*registeredConfigurable, error) {
+	byPriority := slices.Clone(registered)
+	slices.SortStableFunc(byPriority, func(a, b *registeredConfigurable) int {
+		return cmp.Compare(b.options.Priority, a.options.Priority)
+	})
+
+	// dependents[i] holds the indices of the configurables that must be called after configurable i.
+	dependents := make([][]int, len(byPriority))
+	remainingDependencies := make([]int, len(byPriority))
+	for i, configurable := range byPriority {
+		for _, dependency := range configurable.options.DependsOn {
+			for j, other := range byPriority {
+				if i == j || !isPathPrefix(dependency, other.path) {
+					continue
+				}
+
+				dependents[j] = append(dependents[j], i)
+				remainingDependencies[i]++
+			}
+		}
+	}
+
+	// Repeatedly pick the first configurable whose dependencies were all picked, so that the priority order is kept
+	// whenever possible.
+	ordered := make([]*registeredConfigurable, 0, len(byPriority))
+	picked := make([]bool, len(byPriority))
+	for len(ordered) < len(byPriority) {
+		next := -1
+		for i := range byPriority {
+			if !picked[i] && remainingDependencies[i] == 0 {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			return nil, fmt.Errorf("%w: can't order registrations by their dependencies", ErrDependencyCycle)
+		}
+
+		picked[next] = true
+		ordered = append(ordered, byPriority[next])
+		for _, dependent := range dependents[next] {
+			remainingDependencies[dependent]--
+		}
+	}
+
+	return ordered, nil
+}
+
+// Checks whether the path is the prefix path, or a path within it.
+func isPathPrefix(prefix []string, path []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(prefix, path[:len(prefix)])
+}
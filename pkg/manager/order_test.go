@@ -0,0 +1,165 @@
+package manager_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func newInitiatedConfigurationManagerWithTwoDepthLevels(id string) (
+	*manager.DynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels],
+	testutils.MockConfigurationWithTwoDepthLevels,
+	error,
+) {
+	mockConfiguration := testutils.RandomMockConfigurationWithTwoDepthLevels()
+
+	mgr, err := manager.NewDynamicConfigurationManager[testutils.MockConfigurationWithTwoDepthLevels](id)
+	if err != nil {
+		return nil, mockConfiguration, err
+	}
+
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		return nil, mockConfiguration, err
+	}
+
+	return mgr, mockConfiguration, nil
+}
+
+func TestCallbacksAreCalledInOrder(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testCallbackOrder")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	callbackFor := func(name string) func(testutils.MockConfigurationA) error {
+		return func(testutils.MockConfigurationA) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	registrations := []struct {
+		name    string
+		path    []string
+		options manager.RegisterOptions
+	}{
+		{name: "ingest", path: []string{"Second", "A"}, options: manager.RegisterOptions{
+			DependsOn: [][]string{{"First"}},
+		}},
+		{name: "first", path: []string{"First", "A"}},
+		{name: "second", path: []string{"First", "A"}},
+		{name: "important", path: []string{"Second", "A"}, options: manager.RegisterOptions{Priority: 1}},
+	}
+	for _, registration := range registrations {
+		if _, err := mgr.RegisterWithOptions(
+			registration.path,
+			callbackFor(registration.name),
+			registration.options,
+		); err != nil {
+			t.Fatalf("failed to register %s: %#v", registration.name, err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		calls = calls[:0]
+		mockConfiguration.First.A.Value += "bla"
+		mockConfiguration.Second.A.Value += "bla"
+		if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+			t.Fatalf("failed to update configuration: %#v", err)
+		}
+
+		expected := []string{"important", "first", "second", "ingest"}
+		if !slices.Equal(calls, expected) {
+			t.Fatalf("callbacks called in order %v (expected %v)", calls, expected)
+		}
+	}
+}
+
+func TestRestorationIsInReverseOrder(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testRestorationOrder")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	callbackFor := func(name string, fail bool) func(testutils.MockConfigurationA) error {
+		initial := true
+		return func(cfg testutils.MockConfigurationA) error {
+			if initial {
+				initial = false
+				return nil
+			}
+			if fail {
+				return errors.ErrUnsupported
+			}
+			calls = append(calls, name+":"+cfg.Value)
+			return nil
+		}
+	}
+
+	if _, err := mgr.Register([]string{"First", "A"}, callbackFor("storage", false)); err != nil {
+		t.Fatalf("failed to register storage: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "A"}, callbackFor("ingest", false)); err != nil {
+		t.Fatalf("failed to register ingest: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "B"}, func(testutils.MockConfigurationB) error {
+		return errors.ErrUnsupported
+	}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when initial call fails: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"First", "B"}, func(cfg testutils.MockConfigurationB) error {
+		if cfg.Value != mockConfiguration.First.B.Value {
+			return errors.ErrUnsupported
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register rejecting module: %#v", err)
+	}
+
+	origFirst, origSecond := mockConfiguration.First.A.Value, mockConfiguration.Second.A.Value
+	newConfiguration := mockConfiguration
+	newConfiguration.First.A.Value += "bla"
+	newConfiguration.Second.A.Value += "bla"
+	newConfiguration.First.B.Value = !newConfiguration.First.B.Value
+
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+
+	expected := []string{
+		"storage:" + newConfiguration.First.A.Value,
+		"ingest:" + newConfiguration.Second.A.Value,
+		"ingest:" + origSecond,
+		"storage:" + origFirst,
+	}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("callbacks called in order %v (expected %v)", calls, expected)
+	}
+}
+
+func TestRegisterWithDependencyCycle(t *testing.T) {
+	mgr, _, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testDependencyCycle")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	callback := func(testutils.MockConfigurationA) error { return nil }
+
+	if _, err := mgr.RegisterWithOptions([]string{"First", "A"}, callback, manager.RegisterOptions{
+		DependsOn: [][]string{{"Second"}},
+	}); err != nil {
+		t.Fatalf("failed to register First.A: %#v", err)
+	}
+
+	_, err = mgr.RegisterWithOptions([]string{"Second", "A"}, callback, manager.RegisterOptions{
+		DependsOn: [][]string{{"First", "A"}},
+	})
+	if !errors.Is(err, manager.ErrDependencyCycle) {
+		t.Fatalf("wrong error when registering with a dependency cycle: %#v", err)
+	}
+}
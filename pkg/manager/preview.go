@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// The result of previewing a configuration update, see PreviewConfigurationUpdate.
+type UpdatePreview struct {
+	// The registered paths, in the order of registration, along with whether the update changes their configuration.
+	Paths []PathPreview
+	// The registrations whose configuration is changed by the update, in the order in which they would be called.
+	// Registrations called after commit can't reject updates, so they aren't included.
+	Modules []ModulePreview
+	// The changes which the update makes, see Diff.
+	Changes []Change
+	// The changes of static parts of the configuration, which are also included in the changes. Depending on the
+	// static policy, they either make the update rejected or pending a restart, see Options.StaticPaths.
+	StaticChanges []Change
+	// The error with which the update would be rejected before any module is called, i.e. ErrStaticFieldChanged.
+	Err error
+}
+
+// Whether a registered path is changed by a previewed configuration update.
+type PathPreview struct {
+	Path    string
+	Changed bool
+}
+
+// Whether a registration would accept a previewed configuration update.
+type ModulePreview struct {
+	Path string
+	// The name of the registration, as given in its options.
+	Name string
+	// For wildcard paths, the key of the changed entry.
+	Key string
+	// Whether the module validated the new configuration. Only two-phase configurables, and callbacks registered with a
+	// validation function, can validate a configuration without applying it. Other callbacks aren't called by
+	// previews, and are reported as not validated.
+	Validated bool
+	// The error with which the module rejected the new configuration, or nil if it accepted it or didn't validate it.
+	Err error
+}
+
+// Whether the previewed configuration update would be applied, as far as static changes and the validating modules
+// are concerned.
+func (preview UpdatePreview) Accepted() bool {
+	return preview.Err == nil && !slices.ContainsFunc(preview.Modules, func(module ModulePreview) bool {
+		return module.Err != nil
+	})
+}
+
+// Find out what a configuration update would change, and whether the registered modules would accept it, without
+// applying it. Two-phase configurables and validation functions of callbacks (see RegisterOptions.Validate) validate
+// the new configuration, and other registrations aren't called at all.
+// Unlike a configuration update, all of the changed modules are validated, even after one of them rejects the new
+// configuration.
+func (mgr *DynamicConfigurationManager[Configuration]) PreviewConfigurationUpdate(
+	newConfiguration Configuration,
+) (UpdatePreview, error) {
+	return mgr.PreviewConfigurationUpdateContext(context.Background(), newConfiguration)
+}
+
+// Same as PreviewConfigurationUpdate, where the given context is passed to the validating modules.
+func (mgr *DynamicConfigurationManager[Configuration]) PreviewConfigurationUpdateContext(
+	ctx context.Context,
+	newConfiguration Configuration,
+) (UpdatePreview, error) {
+	mgr.configUpdateLock.Lock()
+	defer mgr.configUpdateLock.Unlock()
+	defer mgr.removeUnsubscribed()
+
+	changedConfigurables, changedConfigurations, err := mgr.getChangedConfigurables(newConfiguration, true)
+	if err != nil {
+		return UpdatePreview{}, err
+	}
+
+	changes, staticChanges := mgr.diff(mgr.cfg, newConfiguration)
+	preview := UpdatePreview{
+		Paths:         make([]PathPreview, 0, len(mgr.registered)),
+		Modules:       make([]ModulePreview, 0, len(changedConfigurables)),
+		Changes:       changes,
+		StaticChanges: staticChanges,
+		Err:           mgr.staticChangesError(staticChanges),
+	}
+
+	changedPaths := make(map[string]bool, len(changedConfigurables))
+	for _, configurable := range changedConfigurables {
+		changedPaths[configurable.pathKey] = true
+	}
+
+	previewedPaths := make(map[string]bool, len(mgr.registered))
+	for _, configurable := range mgr.registered {
+		if configurable.unsubscribed.Load() || previewedPaths[configurable.pathKey] {
+			continue
+		}
+		previewedPaths[configurable.pathKey] = true
+
+		preview.Paths = append(preview.Paths, PathPreview{
+			Path:    configurable.pathString,
+			Changed: changedPaths[configurable.pathKey],
+		})
+	}
+
+	changedConfigurables, changedConfigurations, _, _ = splitAfterCommit(changedConfigurables, changedConfigurations)
+	for i, configurable := range changedConfigurables {
+		preview.Modules = append(preview.Modules, ModulePreview{
+			Path:      configurable.pathString,
+			Name:      configurable.options.Name,
+			Key:       changedConfigurations[i].key,
+			Validated: configurable.canValidate(),
+			Err:       configurable.validate(ctx, changedConfigurations[i]),
+		})
+	}
+
+	return preview, nil
+}
+
+// Check whether the registered modules would accept the configuration update, without applying it.
+// See PreviewConfigurationUpdate. The returned error joins the error of rejected static changes, if any, and an
+// *UpdateError for each of the rejecting modules. As a configuration can't be deemed valid by modules which didn't
+// validate it, the error also has an *UpdateError which matches ErrNotValidated for each of them.
+func (mgr *DynamicConfigurationManager[Configuration]) ValidateConfigurationUpdate(
+	newConfiguration Configuration,
+) error {
+	preview, err := mgr.PreviewConfigurationUpdate(newConfiguration)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{preview.Err}
+	for _, module := range preview.Modules {
+		moduleErr := module.Err
+		if !module.Validated {
+			moduleErr = ErrNotValidated
+		}
+
+		if moduleErr != nil {
+			errs = append(errs, &UpdateError{Path: module.Path, Key: module.Key, Name: module.Name, Err: moduleErr})
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,182 @@
+package manager_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func TestPreviewConfigurationUpdate(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testPreviewConfigurationUpdate")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	first := &mockTwoPhaseConfigurable{name: "first", calls: &calls}
+	second := &mockTwoPhaseConfigurable{
+		name:   "second",
+		calls:  &calls,
+		reject: func(cfg testutils.MockConfigurationA) bool { return cfg.Value != mockConfiguration.Second.A.Value },
+	}
+
+	if _, err := mgr.Register([]string{"First", "A"}, first); err != nil {
+		t.Fatalf("failed to register first: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "A"}, second); err != nil {
+		t.Fatalf("failed to register second: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"First", "B"}, func(cfg testutils.MockConfigurationB) error {
+		calls = append(calls, "callback")
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"Second", "B"}, func(cfg testutils.MockConfigurationB) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register unchanged callback: %#v", err)
+	}
+	calls = calls[:0]
+
+	newConfiguration := mockConfiguration
+	newConfiguration.First.A.Value += "bla"
+	newConfiguration.Second.A.Value += "bla"
+	newConfiguration.First.B.Value = !newConfiguration.First.B.Value
+
+	preview, err := mgr.PreviewConfigurationUpdate(newConfiguration)
+	if err != nil {
+		t.Fatalf("failed to preview configuration update: %#v", err)
+	}
+
+	expectedPaths := []manager.PathPreview{
+		{Path: "First.A", Changed: true},
+		{Path: "Second.A", Changed: true},
+		{Path: "First.B", Changed: true},
+		{Path: "Second.B", Changed: false},
+	}
+	if !reflect.DeepEqual(preview.Paths, expectedPaths) {
+		t.Fatalf("expected path previews %v but got %v", expectedPaths, preview.Paths)
+	}
+
+	expectedModules := []manager.ModulePreview{
+		{Path: "First.A", Validated: true},
+		{Path: "Second.A", Validated: true, Err: errors.ErrUnsupported},
+		{Path: "First.B", Validated: false},
+	}
+	if !reflect.DeepEqual(preview.Modules, expectedModules) {
+		t.Fatalf("expected module previews %v but got %v", expectedModules, preview.Modules)
+	}
+
+	if preview.Accepted() {
+		t.Fatalf("preview is accepted even though a module rejected it")
+	}
+	if len(preview.Changes) != 3 {
+		t.Fatalf("expected three changes but got %v", preview.Changes)
+	}
+
+	expectedCalls := []string{"first:validate", "second:validate"}
+	if !slices.Equal(calls, expectedCalls) {
+		t.Fatalf("expected preview to only validate, with calls %v, but got %v", expectedCalls, calls)
+	}
+
+	if err := mgr.ValidateConfigurationUpdate(newConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when validating illegal configuration: %#v", err)
+	}
+
+	current, err := manager.GetAs[testutils.MockConfigurationWithTwoDepthLevels](mgr, []string{})
+	if err != nil {
+		t.Fatalf("failed to get configuration: %#v", err)
+	}
+	if current != mockConfiguration {
+		t.Fatalf("configuration changed by preview: expected %#v but got %#v", mockConfiguration, current)
+	}
+
+	// The callback can't validate its configuration, so the configuration can't be deemed valid while it changes.
+	newConfiguration.Second.A = mockConfiguration.Second.A
+	err = mgr.ValidateConfigurationUpdate(newConfiguration)
+	var updateError *manager.UpdateError
+	if !errors.Is(err, manager.ErrNotValidated) || !errors.As(err, &updateError) || updateError.Path != "First.B" {
+		t.Fatalf("wrong error when validating configuration which a callback can't validate: %#v", err)
+	}
+
+	newConfiguration.First.B = mockConfiguration.First.B
+	if err := mgr.ValidateConfigurationUpdate(newConfiguration); err != nil {
+		t.Fatalf("failed to validate legal configuration: %#v", err)
+	}
+}
+
+func TestCallbackValidationFunction(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithTwoDepthLevels("testCallbackValidationFunction")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := make([]string, 0)
+	initialB := mockConfiguration.First.B
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"First", "B"},
+		func(cfg testutils.MockConfigurationB) error {
+			calls = append(calls, "callback")
+			return nil
+		},
+		manager.RegisterOptions{Validate: func(cfg testutils.MockConfigurationB) error {
+			calls = append(calls, "validate")
+			if cfg != initialB {
+				return errors.ErrUnsupported
+			}
+			return nil
+		}},
+	); err != nil {
+		t.Fatalf("failed to register callback with validation function: %#v", err)
+	}
+	if _, err := mgr.Register([]string{"First", "A"}, func(cfg testutils.MockConfigurationA) error {
+		calls = append(calls, "other")
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register other callback: %#v", err)
+	}
+
+	expectedCalls := []string{"validate", "callback", "other"}
+	if !slices.Equal(calls, expectedCalls) {
+		t.Fatalf("expected registration calls %v but got %v", expectedCalls, calls)
+	}
+	calls = calls[:0]
+
+	newConfiguration := mockConfiguration
+	newConfiguration.First.A.Value += "bla"
+	newConfiguration.First.B.Value = !newConfiguration.First.B.Value
+
+	preview, err := mgr.PreviewConfigurationUpdate(newConfiguration)
+	if err != nil {
+		t.Fatalf("failed to preview configuration update: %#v", err)
+	}
+	expectedModules := []manager.ModulePreview{
+		{Path: "First.B", Validated: true, Err: errors.ErrUnsupported},
+		{Path: "First.A", Validated: false},
+	}
+	if !reflect.DeepEqual(preview.Modules, expectedModules) {
+		t.Fatalf("expected module previews %v but got %v", expectedModules, preview.Modules)
+	}
+
+	// The validation function rejects the update before any module is given the new configuration.
+	calls = calls[:0]
+	if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("wrong error when updating to illegal configuration: %#v", err)
+	}
+	if !slices.Equal(calls, []string{"validate"}) {
+		t.Fatalf("expected only validation upon rejected update, but got calls %v", calls)
+	}
+
+	if _, err := mgr.RegisterWithOptions(
+		[]string{"First", "B"},
+		func(cfg testutils.MockConfigurationB) error { return nil },
+		manager.RegisterOptions{Validate: func(cfg testutils.MockConfigurationA) error { return nil }},
+	); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when registering validation function of the wrong type: %#v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// What to do upon configuration updates which change static parts of the configuration, see Options.StaticPaths.
+// The first configuration update is never affected, as it sets the configuration which is read at startup.
+type StaticPolicy int
+
+const (
+	// Reject configuration updates which change static parts of the configuration with ErrStaticFieldChanged.
+	RejectStaticChanges StaticPolicy = iota
+	// Apply configuration updates which change static parts of the configuration, and report these changes as pending
+	// a restart, see PendingRestart.
+	PendingRestartOnStaticChanges
+)
+
+// Get the changes of static parts of the configuration which were applied since startup, and take effect only once
+// the process restarts. Each change is from the value at startup to the current value, so changes which were undone
+// by later configuration updates aren't returned.
+// This doesn't wait for configuration updates in progress, so it may be called from callbacks and observers.
+func (mgr *DynamicConfigurationManager[Configuration]) PendingRestart() []Change {
+	return slices.Clone(*mgr.pendingRestart.Load())
+}
+
+// Reject the static changes of a configuration update, unless they are allowed by the static policy.
+func (mgr *DynamicConfigurationManager[Configuration]) checkStaticChanges(staticChanges []Change) error {
+	err := mgr.staticChangesError(staticChanges)
+	if err != nil {
+		mgr.metrics.staticFieldChanged.Inc()
+	}
+
+	return err
+}
+
+func (mgr *DynamicConfigurationManager[Configuration]) staticChangesError(staticChanges []Change) error {
+	if len(staticChanges) == 0 || mgr.version.Load() == 0 || mgr.options.StaticPolicy == PendingRestartOnStaticChanges {
+		return nil
+	}
+
+	paths := make([]string, 0, len(staticChanges))
+	for _, change := range staticChanges {
+		paths = append(paths, change.Path)
+	}
+
+	return fmt.Errorf("%w: %s", ErrStaticFieldChanged, strings.Join(paths, ", "))
+}
+
+// Merge the static changes of an applied configuration update into the changes pending a restart.
+func (mgr *DynamicConfigurationManager[Configuration]) recordPendingRestart(staticChanges []Change) {
+	if mgr.version.Load() == 0 {
+		return
+	}
+
+	// The changes are published as a new slice, so that readers of the previous one aren't affected.
+	pendingRestart := slices.Clone(*mgr.pendingRestart.Load())
+	for _, change := range staticChanges {
+		index := slices.IndexFunc(pendingRestart, func(pending Change) bool {
+			return pending.Path == change.Path
+		})
+		if index == -1 {
+			pendingRestart = append(pendingRestart, change)
+			continue
+		}
+
+		pending := &pendingRestart[index]
+		pending.New = change.New
+		switch {
+		case pending.Kind == EntryAdded && change.Kind == EntryRemoved:
+			pendingRestart = slices.Delete(pendingRestart, index, index+1)
+			continue
+		case pending.Kind == EntryRemoved && change.Kind == EntryAdded:
+			pending.Kind = EntryModified
+		case change.Kind == EntryRemoved:
+			pending.Kind = EntryRemoved
+		}
+
+		if pending.Kind == EntryModified && mgr.equal(pending.Old, pending.New) {
+			pendingRestart = slices.Delete(pendingRestart, index, index+1)
+		}
+	}
+
+	mgr.pendingRestart.Store(&pendingRestart)
+	mgr.metrics.pendingRestartPaths.Store(int64(len(pendingRestart)))
+}
@@ -0,0 +1,140 @@
+package manager_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func initialServerConfiguration() testutils.MockConfigurationWithServer {
+	return testutils.MockConfigurationWithServer{
+		Server: testutils.MockConfigurationServer{Port: 8080, DataDir: "/var/lib/data", LogLevel: "info"},
+	}
+}
+
+func TestStaticChangesRejected(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithServer](
+		"testStaticChangesRejected",
+		manager.Options{StaticPaths: [][]string{{"Server", "DataDir"}}},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := initialServerConfiguration()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	calls := 0
+	if _, err := mgr.Register([]string{"Server"}, func(cfg testutils.MockConfigurationServer) error {
+		calls++
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	mockConfiguration.Server.LogLevel = "debug"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update a dynamic field: %#v", err)
+	}
+
+	for _, update := range []func(cfg *testutils.MockConfigurationWithServer){
+		func(cfg *testutils.MockConfigurationWithServer) { cfg.Server.Port = 9090 },
+		func(cfg *testutils.MockConfigurationWithServer) { cfg.Server.DataDir = "/tmp" },
+	} {
+		newConfiguration := mockConfiguration
+		update(&newConfiguration)
+
+		preview, err := mgr.PreviewConfigurationUpdate(newConfiguration)
+		if err != nil {
+			t.Fatalf("failed to preview configuration update: %#v", err)
+		}
+		if preview.Accepted() || !errors.Is(preview.Err, manager.ErrStaticFieldChanged) {
+			t.Fatalf("preview of a static change wasn't rejected: %#v", preview)
+		}
+
+		if err := mgr.OnConfigurationUpdate(newConfiguration); !errors.Is(err, manager.ErrStaticFieldChanged) {
+			t.Fatalf("wrong error when updating a static field: %#v", err)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected callback to be called upon registration and the dynamic update but got %d calls", calls)
+	}
+	if cfg := mgr.Snapshot(); cfg != mockConfiguration {
+		t.Fatalf("expected configuration %v after rejected static changes but got %v", mockConfiguration, cfg)
+	}
+	if pending := mgr.PendingRestart(); len(pending) != 0 {
+		t.Fatalf("expected no changes pending a restart but got %v", pending)
+	}
+}
+
+func TestStaticChangesPendingRestart(t *testing.T) {
+	mgr, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithServer](
+		"testStaticChangesPendingRestart",
+		manager.Options{StaticPolicy: manager.PendingRestartOnStaticChanges},
+	)
+	if err != nil {
+		t.Fatalf("failed to initiate configuration manager: %#v", err)
+	}
+
+	mockConfiguration := initialServerConfiguration()
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+	if pending := mgr.PendingRestart(); len(pending) != 0 {
+		t.Fatalf("expected the first configuration not to be pending a restart but got %v", pending)
+	}
+
+	// Callbacks may get the changes pending a restart, which are those before the update until it is applied.
+	pendingInCallback := make([][]manager.Change, 0)
+	if _, err := mgr.Register([]string{"Server"}, func(cfg testutils.MockConfigurationServer) error {
+		pendingInCallback = append(pendingInCallback, mgr.PendingRestart())
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register callback: %#v", err)
+	}
+
+	mockConfiguration.Server.Port = 9090
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update a static field: %#v", err)
+	}
+	mockConfiguration.Server.Port = 9091
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update a static field: %#v", err)
+	}
+
+	expectedPending := []manager.Change{{Path: "Server.Port", Kind: manager.EntryModified, Old: 8080, New: 9091}}
+	if pending := mgr.PendingRestart(); !reflect.DeepEqual(pending, expectedPending) {
+		t.Fatalf("expected changes pending a restart %v but got %v", expectedPending, pending)
+	}
+	expectedInCallback := [][]manager.Change{
+		{},
+		{},
+		{{Path: "Server.Port", Kind: manager.EntryModified, Old: 8080, New: 9090}},
+	}
+	if !reflect.DeepEqual(pendingInCallback, expectedInCallback) {
+		t.Fatalf("expected changes pending a restart in callback %v but got %v", expectedInCallback, pendingInCallback)
+	}
+
+	mockConfiguration.Server.Port = 8080
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update a static field: %#v", err)
+	}
+	if pending := mgr.PendingRestart(); len(pending) != 0 {
+		t.Fatalf("expected no changes pending a restart once undone but got %v", pending)
+	}
+}
+
+func TestUnknownStaticPath(t *testing.T) {
+	if _, err := manager.NewDynamicConfigurationManagerWithOptions[testutils.MockConfigurationWithServer](
+		"testUnknownStaticPath",
+		manager.Options{StaticPaths: [][]string{{"Server", "DataDirectory"}}},
+	); !errors.Is(err, manager.ErrNoMatchingFieldFound) {
+		t.Fatalf("wrong error when initiating configuration manager with unknown static path: %#v", err)
+	}
+}
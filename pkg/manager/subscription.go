@@ -0,0 +1,39 @@
+package manager
+
+import "sync"
+
+// A handle to a registered callback. Closing it removes the registration, so that the callback is no longer called
+// upon configuration updates.
+type Subscription struct {
+	once        sync.Once
+	unsubscribe func()
+}
+
+// Creates a subscription whose removal is performed by the given function. The function is called at most once.
+// This is mostly useful for mocks and wrappers of the configuration manager.
+func NewSubscription(unsubscribe func()) *Subscription {
+	return &Subscription{unsubscribe: unsubscribe}
+}
+
+// Remove the registration. Once this returns the callback will not be called again, even if this is called from
+// within the callback itself while a configuration update is in progress. When this is called from another goroutine
+// during a configuration update, a call of the callback which the update already started may still be in progress,
+// and it isn't waited for, but no further call starts.
+// Calling this more than once, or on a nil subscription, does nothing.
+func (subscription *Subscription) Unsubscribe() {
+	if subscription == nil {
+		return
+	}
+
+	subscription.once.Do(func() {
+		if subscription.unsubscribe != nil {
+			subscription.unsubscribe()
+		}
+	})
+}
+
+// Same as Unsubscribe, allowing the subscription to be used as an io.Closer. It never fails.
+func (subscription *Subscription) Close() error {
+	subscription.Unsubscribe()
+	return nil
+}
@@ -0,0 +1,86 @@
+package manager
+
+import "context"
+
+// Register a typed callback to be called upon dynamic configuration change.
+// This is the same as DynamicConfigurationManager.Register, except that the callback's signature is checked at compile
+// time. The type of the configuration under the path is still checked upon registration, and ErrBadCallback is
+// returned if it doesn't match.
+func Subscribe[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	callback func(T) error,
+) (*Subscription, error) {
+	return mgr.Register(path, callback)
+}
+
+// Register a typed context-aware callback to be called upon dynamic configuration change.
+// See Subscribe.
+func SubscribeContext[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	callback func(context.Context, T) error,
+) (*Subscription, error) {
+	return mgr.Register(path, callback)
+}
+
+// Register a typed callback which receives both the old and the new configuration under the path. See Register.
+func SubscribeChanges[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	callback func(old T, new T) error,
+) (*Subscription, error) {
+	return mgr.Register(path, callback)
+}
+
+// Register a typed callback which receives both the old and the new configuration under the path, and whether this is
+// the initial call from Register, on which the old configuration is the zero value. See Register.
+func SubscribeChangesWithInitial[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	callback func(initial bool, old T, new T) error,
+) (*Subscription, error) {
+	return mgr.Register(path, callback)
+}
+
+// Register a typed callback to be called for each entry of a wildcard path that is added or modified.
+// See Register.
+func SubscribeEntries[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	callback func(key string, configuration T) error,
+) (*Subscription, error) {
+	return mgr.Register(path, callback)
+}
+
+// Register a typed callback to be called for each entry of a wildcard path that is added, modified or removed.
+// See Register.
+func SubscribeEntryEvents[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	callback func(key string, event EntryEvent, configuration T) error,
+) (*Subscription, error) {
+	return mgr.Register(path, callback)
+}
+
+// Get the current value of a part of the configuration as the given type.
+// This is the same as DynamicConfigurationManager.Get, except that no out parameter is needed. ErrBadType is returned
+// if the configuration under the path is of a different type.
+func GetAs[T any, Configuration any](mgr *DynamicConfigurationManager[Configuration], path []string) (T, error) {
+	var out T
+	if err := mgr.Get(path, &out); err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
+
+// Register a typed two-phase configurable to be notified upon dynamic configuration change.
+// See TwoPhaseConfigurable for the two-phase protocol.
+func SubscribeConfigurable[Configuration any, T any](
+	mgr *DynamicConfigurationManager[Configuration],
+	path []string,
+	configurable TwoPhaseConfigurable[T],
+) (*Subscription, error) {
+	return mgr.Register(path, configurable)
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"context"
+	"sync"
+)
+
+// Anything that callbacks can be registered on by path, such as the manager, or a gettable of the getter package.
+type Registerer interface {
+	Register(path []string, callback any) (*Subscription, error)
+}
+
+// Watch the configuration under the path through a channel, instead of registering a callback.
+// The current configuration is delivered immediately, followed by every change of it. The channel holds a single
+// configuration: if the receiver falls behind, a pending configuration is replaced by the newer one, so the receiver
+// always gets the latest configuration. Watching never rejects a configuration.
+// Once the context is done, the registration is removed and the channel is closed.
+func Watch[T any](ctx context.Context, registerer Registerer, path []string) (<-chan T, error) {
+	watcher := &watcher[T]{updates: make(chan T, 1)}
+
+	subscription, err := registerer.Register(path, func(configuration T) error {
+		watcher.offer(configuration)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		subscription.Unsubscribe()
+		watcher.close()
+	}()
+
+	return watcher.updates, nil
+}
+
+type watcher[T any] struct {
+	lock    sync.Mutex
+	updates chan T
+	closed  bool
+}
+
+// Deliver the configuration, replacing a configuration which wasn't received yet.
+func (watcher *watcher[T]) offer(configuration T) {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	if watcher.closed {
+		return
+	}
+
+	select {
+	case <-watcher.updates:
+	default:
+	}
+	watcher.updates <- configuration
+}
+
+func (watcher *watcher[T]) close() {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	watcher.closed = true
+	close(watcher.updates)
+}
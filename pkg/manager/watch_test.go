@@ -0,0 +1,77 @@
+package manager_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/groundcover-com/dynconf/internal/testutils"
+	"github.com/groundcover-com/dynconf/pkg/manager"
+)
+
+func TestWatch(t *testing.T) {
+	mgr, mockConfiguration, err := newInitiatedConfigurationManagerWithOneDepthLevel("testWatch")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := manager.Watch[testutils.MockConfigurationA](ctx, mgr, []string{"A"})
+	if err != nil {
+		t.Fatalf("failed to watch path: %#v", err)
+	}
+
+	if cfg := <-updates; cfg != mockConfiguration.A {
+		t.Fatalf("expected current configuration %#v but got %#v", mockConfiguration.A, cfg)
+	}
+
+	// The receiver falls behind by two updates, and only gets the latest one.
+	for i := 0; i < 2; i++ {
+		mockConfiguration.A.Value += "bla"
+		if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+			t.Fatalf("failed to update configuration: %#v", err)
+		}
+	}
+
+	if cfg := <-updates; cfg != mockConfiguration.A {
+		t.Fatalf("expected latest configuration %#v but got %#v", mockConfiguration.A, cfg)
+	}
+	select {
+	case cfg := <-updates:
+		t.Fatalf("expected no pending configuration, but got %#v", cfg)
+	default:
+	}
+
+	cancel()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Fatalf("expected channel to be closed once the context is done")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("channel wasn't closed once the context was done")
+	}
+
+	mockConfiguration.A.Value += "bla"
+	if err := mgr.OnConfigurationUpdate(mockConfiguration); err != nil {
+		t.Fatalf("failed to update configuration after the watch ended: %#v", err)
+	}
+}
+
+func TestWatchWithWrongType(t *testing.T) {
+	mgr, _, err := newInitiatedConfigurationManagerWithOneDepthLevel("testWatchWithWrongType")
+	if err != nil {
+		t.Fatalf("failed to initiate configuration: %#v", err)
+	}
+
+	if _, err := manager.Watch[testutils.MockConfigurationB](
+		context.Background(),
+		mgr,
+		[]string{"A"},
+	); !errors.Is(err, manager.ErrBadCallback) {
+		t.Fatalf("wrong error when watching path of another type: %#v", err)
+	}
+}